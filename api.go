@@ -39,6 +39,7 @@ func (server *Server) runCaptureLoop(interval time.Duration, capturers *capturer
 		}
 		<-timer.C
 	}
+	capturers.Stop()
 }
 
 // startCapture starts the capture loop if not already started
@@ -0,0 +1,310 @@
+package awscloudwatch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/hyperpilotio/ingestor/capturer/awscommon"
+	"github.com/hyperpilotio/ingestor/capturer/awsecs"
+	"github.com/hyperpilotio/ingestor/database"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+const (
+	ecsNamespace = "AWS/ECS"
+
+	// ECS publishes its metrics once per minute
+	metricPeriod = 60
+
+	// metricDelay leaves time for CloudWatch to aggregate the latest datapoints
+	// before a window is queried, as they are never queried twice.
+	metricDelay = 2 * time.Minute
+
+	// maxMetricDataQueries is the maximum number of queries GetMetricData accepts
+	maxMetricDataQueries = 500
+
+	defaultTableName         = "metrics"
+	defaultCapturesTableName = "metricCaptures"
+)
+
+// clusterMetrics are collected per cluster, reservations only exist at cluster level
+var clusterMetrics = []string{
+	"CPUUtilization",
+	"MemoryUtilization",
+	"CPUReservation",
+	"MemoryReservation",
+}
+
+var serviceMetrics = []string{
+	"CPUUtilization",
+	"MemoryUtilization",
+}
+
+type MetricPoint struct {
+	Region      string    `json:"Region" bson:"Region"`
+	ClusterName string    `json:"ClusterName" bson:"ClusterName"`
+	ServiceName string    `json:"ServiceName" bson:"ServiceName"`
+	MetricName  string    `json:"MetricName" bson:"MetricName"`
+	Timestamp   time.Time `json:"Timestamp" bson:"Timestamp"`
+	Value       float64   `json:"Value" bson:"Value"`
+}
+
+// MetricCapture records the last window captured for a region, it is kept
+// apart from the points so they stay one document per datapoint.
+type MetricCapture struct {
+	Region            string    `json:"Region" bson:"Region"`
+	StartTime         time.Time `json:"StartTime" bson:"StartTime"`
+	EndTime           time.Time `json:"EndTime" bson:"EndTime"`
+	Points            int       `json:"Points" bson:"Points"`
+	ThrottledRequests int64     `json:"ThrottledRequests" bson:"ThrottledRequests"`
+}
+
+type metricQuery struct {
+	clusterName string
+	serviceName string
+	metricName  string
+}
+
+type AWSCloudWatchCapturer struct {
+	Region    string
+	Sess      *session.Session
+	DB        *database.MongoDB
+	CaptureDB *database.MongoDB
+	Interval  time.Duration
+	Filter    *awsecs.ClusterFilter
+	Throttles *awscommon.ThrottleCounter
+
+	lastEndTime time.Time
+}
+
+func NewCapturer(config *viper.Viper, region string, interval time.Duration) (*AWSCloudWatchCapturer, error) {
+	db, dbErr := database.NewDB(config)
+	if dbErr != nil {
+		return nil, dbErr
+	}
+
+	db.TableName = config.GetString("cloudwatch.tableName")
+	if db.TableName == "" {
+		db.TableName = defaultTableName
+	}
+
+	captureDB := *db
+	captureDB.TableName = config.GetString("cloudwatch.capturesTableName")
+	if captureDB.TableName == "" {
+		captureDB.TableName = defaultCapturesTableName
+	}
+
+	// clusters excluded from the ECS capture are not queried either
+	filter, err := awsecs.NewClusterFilter(config, region)
+	if err != nil {
+		return nil, errors.New("Unable to create cluster filter: " + err.Error())
+	}
+
+	session, err := awscommon.NewSession(config, region)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AWSCloudWatchCapturer{
+		Region:    region,
+		Sess:      session,
+		DB:        db,
+		CaptureDB: &captureDB,
+		Interval:  interval,
+		Filter:    filter,
+		Throttles: awscommon.NewThrottleCounter(session),
+	}, nil
+}
+
+func (capturer *AWSCloudWatchCapturer) Capture() error {
+	endTime := time.Now().Add(-metricDelay).Truncate(time.Minute)
+	startTime := capturer.lastEndTime
+	if startTime.IsZero() {
+		startTime = endTime.Add(-capturer.Interval)
+	}
+
+	if !endTime.After(startTime) {
+		return nil
+	}
+
+	points, err := capturer.GetMetrics(startTime, endTime)
+	throttled := capturer.Throttles.Reset()
+	if throttled > 0 {
+		glog.Warningf("%d requests throttled while capturing metrics of region %s", throttled, capturer.Region)
+	}
+	if err != nil {
+		return errors.New("Unable to get cloudwatch metrics: " + err.Error())
+	}
+
+	if len(points) > 0 {
+		if err := capturer.DB.Insert(points...); err != nil {
+			return errors.New("Unable to write cloudwatch metrics: " + err.Error())
+		}
+	}
+	capturer.lastEndTime = endTime
+
+	metricCapture := MetricCapture{
+		Region:            capturer.Region,
+		StartTime:         startTime,
+		EndTime:           endTime,
+		Points:            len(points),
+		ThrottledRequests: throttled,
+	}
+	selector := bson.M{"Region": capturer.Region}
+	if err := capturer.CaptureDB.Upsert(selector, metricCapture); err != nil {
+		return errors.New("Unable to write cloudwatch capture: " + err.Error())
+	}
+
+	return nil
+}
+
+// GetMetrics returns the ECS cluster and service utilization points
+// published within [startTime, endTime).
+func (capturer *AWSCloudWatchCapturer) GetMetrics(startTime time.Time, endTime time.Time) ([]interface{}, error) {
+	glog.V(1).Infof("GetMetrics for region: %s", capturer.Region)
+
+	ecsSvc := ecs.New(capturer.Sess)
+	cloudwatchSvc := cloudwatch.New(capturer.Sess)
+
+	queries, err := capturer.listMetricQueries(ecsSvc)
+	if err != nil {
+		return nil, err
+	}
+
+	points := []interface{}{}
+	for start := 0; start < len(queries); start += maxMetricDataQueries {
+		end := start + maxMetricDataQueries
+		if end > len(queries) {
+			end = len(queries)
+		}
+
+		batch := make(map[string]metricQuery)
+		metricDataQueries := []*cloudwatch.MetricDataQuery{}
+		for i, query := range queries[start:end] {
+			id := fmt.Sprintf("m%d", i)
+			batch[id] = query
+			metricDataQueries = append(metricDataQueries, query.toMetricDataQuery(id))
+		}
+
+		getMetricDataInput := &cloudwatch.GetMetricDataInput{
+			MetricDataQueries: metricDataQueries,
+			StartTime:         aws.Time(startTime),
+			EndTime:           aws.Time(endTime),
+		}
+		err := cloudwatchSvc.GetMetricDataPages(getMetricDataInput, func(page *cloudwatch.GetMetricDataOutput, lastPage bool) bool {
+			for _, result := range page.MetricDataResults {
+				query, ok := batch[aws.StringValue(result.Id)]
+				if !ok {
+					continue
+				}
+
+				for i, timestamp := range result.Timestamps {
+					if i >= len(result.Values) {
+						break
+					}
+					points = append(points, MetricPoint{
+						Region:      capturer.Region,
+						ClusterName: query.clusterName,
+						ServiceName: query.serviceName,
+						MetricName:  query.metricName,
+						Timestamp:   aws.TimeValue(timestamp),
+						Value:       aws.Float64Value(result.Values[i]),
+					})
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, errors.New("Unable to get metric data: " + err.Error())
+		}
+	}
+
+	return points, nil
+}
+
+// listMetricQueries builds the metric queries for every cluster and service
+// in the region that matches the cluster filter
+func (capturer *AWSCloudWatchCapturer) listMetricQueries(ecsSvc *ecs.ECS) ([]metricQuery, error) {
+	clusters, err := awsecs.DescribeClusters(ecsSvc, capturer.Filter, capturer.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := []metricQuery{}
+	for _, cluster := range clusters {
+		clusterName := aws.StringValue(cluster.ClusterName)
+		for _, metricName := range clusterMetrics {
+			queries = append(queries, metricQuery{
+				clusterName: clusterName,
+				metricName:  metricName,
+			})
+		}
+
+		listServicesInput := &ecs.ListServicesInput{
+			Cluster: cluster.ClusterArn,
+		}
+		err := ecsSvc.ListServicesPages(listServicesInput, func(page *ecs.ListServicesOutput, lastPage bool) bool {
+			for _, serviceArn := range page.ServiceArns {
+				serviceName := nameFromArn(aws.StringValue(serviceArn))
+				for _, metricName := range serviceMetrics {
+					queries = append(queries, metricQuery{
+						clusterName: clusterName,
+						serviceName: serviceName,
+						metricName:  metricName,
+					})
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, errors.New("Unable to list services of cluster " + clusterName + ": " + err.Error())
+		}
+	}
+
+	return queries, nil
+}
+
+func (query metricQuery) toMetricDataQuery(id string) *cloudwatch.MetricDataQuery {
+	dimensions := []*cloudwatch.Dimension{
+		{
+			Name:  aws.String("ClusterName"),
+			Value: aws.String(query.clusterName),
+		},
+	}
+	if query.serviceName != "" {
+		dimensions = append(dimensions, &cloudwatch.Dimension{
+			Name:  aws.String("ServiceName"),
+			Value: aws.String(query.serviceName),
+		})
+	}
+
+	return &cloudwatch.MetricDataQuery{
+		Id: aws.String(id),
+		MetricStat: &cloudwatch.MetricStat{
+			Metric: &cloudwatch.Metric{
+				Namespace:  aws.String(ecsNamespace),
+				MetricName: aws.String(query.metricName),
+				Dimensions: dimensions,
+			},
+			Period: aws.Int64(metricPeriod),
+			Stat:   aws.String(cloudwatch.StatisticAverage),
+		},
+	}
+}
+
+// nameFromArn returns the resource name at the end of an ECS ARN, it handles
+// both arn:...:service/name and the newer arn:...:service/cluster/name formats.
+func nameFromArn(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
@@ -0,0 +1,163 @@
+package awscloudwatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/hyperpilotio/ingestor/capturer/awscommon"
+	"github.com/hyperpilotio/ingestor/capturer/awsecs"
+)
+
+const getMetricDataResponse = `<GetMetricDataResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">
+  <GetMetricDataResult>
+    <MetricDataResults>%s</MetricDataResults>
+  </GetMetricDataResult>
+  <ResponseMetadata>
+    <RequestId>8a5a4c0e-5f4d-11ea-bc55-0242ac130003</RequestId>
+  </ResponseMetadata>
+</GetMetricDataResponse>`
+
+const metricDataResult = `
+      <member>
+        <Id>%s</Id>
+        <StatusCode>Complete</StatusCode>
+        <Timestamps><member>2020-03-06T10:00:00Z</member></Timestamps>
+        <Values><member>42.5</member></Values>
+      </member>`
+
+const (
+	testClusterArn         = "arn:aws:ecs:us-east-1:123456789012:cluster/web"
+	testExcludedClusterArn = "arn:aws:ecs:us-east-1:123456789012:cluster/batch"
+)
+
+// newTestServer stands in for ECS and CloudWatch, ECS requests are told apart
+// by their X-Amz-Target header and CloudWatch ones by their Action parameter.
+func newTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); target != "" {
+			var request struct {
+				Cluster  string
+				Clusters []string
+			}
+			json.NewDecoder(r.Body).Decode(&request)
+
+			var response interface{}
+			switch target[strings.LastIndex(target, ".")+1:] {
+			case "ListClusters":
+				response = map[string]interface{}{
+					"clusterArns": []string{testClusterArn, testExcludedClusterArn},
+				}
+			case "DescribeClusters":
+				clusters := []interface{}{}
+				for _, clusterArn := range request.Clusters {
+					if clusterArn == testExcludedClusterArn {
+						t.Errorf("Excluded cluster %s was described", clusterArn)
+					}
+					clusters = append(clusters, map[string]interface{}{
+						"clusterArn":  clusterArn,
+						"clusterName": clusterArn[strings.LastIndex(clusterArn, "/")+1:],
+					})
+				}
+				response = map[string]interface{}{"clusters": clusters}
+			case "ListServices":
+				if request.Cluster != testClusterArn {
+					t.Errorf("Unexpected services request for cluster %s", request.Cluster)
+				}
+				response = map[string]interface{}{
+					"serviceArns": []string{"arn:aws:ecs:us-east-1:123456789012:service/web/frontend"},
+				}
+			default:
+				t.Errorf("Unexpected ECS request %s", target)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("Action") != "GetMetricData" {
+			t.Errorf("Unexpected CloudWatch request %v", r.PostForm)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		results := ""
+		for i := 1; r.PostForm.Get(fmt.Sprintf("MetricDataQueries.member.%d.Id", i)) != ""; i++ {
+			results += fmt.Sprintf(metricDataResult, r.PostForm.Get(fmt.Sprintf("MetricDataQueries.member.%d.Id", i)))
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, getMetricDataResponse, results)
+	}))
+}
+
+func TestGetMetricsAgainstEndpointOverride(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	os.Setenv("awsId", "AKIDTEST")
+	os.Setenv("awsSecret", "secret")
+
+	config := viper.New()
+	config.Set("endpoints", map[string]string{
+		"ecs":        server.URL,
+		"cloudwatch": server.URL,
+	})
+	config.Set("throttling.maxRetries", 0)
+	config.Set("clusterFilters", map[string]interface{}{
+		"default": map[string]interface{}{
+			"exclude": []string{"batch"},
+		},
+	})
+
+	sess, err := awscommon.NewSession(config, "us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to create session: %s", err.Error())
+	}
+
+	filter, err := awsecs.NewClusterFilter(config, "us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to create cluster filter: %s", err.Error())
+	}
+
+	capturer := &AWSCloudWatchCapturer{
+		Region:    "us-east-1",
+		Sess:      sess,
+		Interval:  5 * time.Minute,
+		Filter:    filter,
+		Throttles: awscommon.NewThrottleCounter(sess),
+	}
+	endTime := time.Date(2020, 3, 6, 10, 5, 0, 0, time.UTC)
+	points, err := capturer.GetMetrics(endTime.Add(-capturer.Interval), endTime)
+	if err != nil {
+		t.Fatalf("Unable to get metrics: %s", err.Error())
+	}
+
+	// one point per cluster metric and per service metric of the included cluster
+	expected := len(clusterMetrics) + len(serviceMetrics)
+	if len(points) != expected {
+		t.Fatalf("Expected %d points, got %d", expected, len(points))
+	}
+
+	services := 0
+	for _, point := range points {
+		metricPoint := point.(MetricPoint)
+		if metricPoint.ClusterName != "web" || metricPoint.Value != 42.5 {
+			t.Errorf("Unexpected point %+v", metricPoint)
+		}
+		if metricPoint.ServiceName == "frontend" {
+			services++
+		}
+	}
+	if services != len(serviceMetrics) {
+		t.Errorf("Expected %d service points, got %d", len(serviceMetrics), services)
+	}
+}
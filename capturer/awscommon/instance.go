@@ -0,0 +1,86 @@
+package awscommon
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type Tag struct {
+	Key   string `json:"Key" bson:"Key"`
+	Value string `json:"Value" bson:"Value"`
+}
+
+type SecurityGroup struct {
+	GroupId   string `json:"GroupId" bson:"GroupId"`
+	GroupName string `json:"GroupName" bson:"GroupName"`
+}
+
+// Instance is the EC2 instance record shared by the ECS container instances
+// and the EC2 inventory.
+type Instance struct {
+	InstanceId       string          `json:"InstanceId" bson:"InstanceId"`
+	InstanceType     string          `json:"InstanceType" bson:"InstanceType"`
+	LaunchTime       time.Time       `json:"LaunchTime" bson:"LaunchTime"`
+	State            string          `json:"State" bson:"State"`
+	Lifecycle        string          `json:"Lifecycle" bson:"Lifecycle"`
+	Platform         string          `json:"Platform" bson:"Platform"`
+	ImageId          string          `json:"ImageId" bson:"ImageId"`
+	AvailabilityZone string          `json:"AvailabilityZone" bson:"AvailabilityZone"`
+	VpcId            string          `json:"VpcId" bson:"VpcId"`
+	SubnetId         string          `json:"SubnetId" bson:"SubnetId"`
+	PrivateIpAddress string          `json:"PrivateIpAddress" bson:"PrivateIpAddress"`
+	PrivateDnsName   string          `json:"PrivateDnsName" bson:"PrivateDnsName"`
+	PublicIpAddress  string          `json:"PublicIpAddress" bson:"PublicIpAddress"`
+	PublicDnsName    string          `json:"PublicDnsName" bson:"PublicDnsName"`
+	Tags             []Tag           `json:"Tags" bson:"Tags"`
+	SecurityGroups   []SecurityGroup `json:"SecurityGroups" bson:"SecurityGroups"`
+}
+
+func NewInstance(instance *ec2.Instance) *Instance {
+	deployInstance := &Instance{}
+	deployInstance.InstanceId = aws.StringValue(instance.InstanceId)
+	deployInstance.InstanceType = aws.StringValue(instance.InstanceType)
+	deployInstance.LaunchTime = aws.TimeValue(instance.LaunchTime)
+	deployInstance.Platform = aws.StringValue(instance.Platform)
+	deployInstance.ImageId = aws.StringValue(instance.ImageId)
+	deployInstance.VpcId = aws.StringValue(instance.VpcId)
+	deployInstance.SubnetId = aws.StringValue(instance.SubnetId)
+	deployInstance.PrivateIpAddress = aws.StringValue(instance.PrivateIpAddress)
+	deployInstance.PrivateDnsName = aws.StringValue(instance.PrivateDnsName)
+	deployInstance.PublicIpAddress = aws.StringValue(instance.PublicIpAddress)
+	deployInstance.PublicDnsName = aws.StringValue(instance.PublicDnsName)
+	if instance.Placement != nil {
+		deployInstance.AvailabilityZone = aws.StringValue(instance.Placement.AvailabilityZone)
+	}
+	if instance.State != nil {
+		deployInstance.State = aws.StringValue(instance.State.Name)
+	}
+
+	// InstanceLifecycle is only set for spot and scheduled instances
+	deployInstance.Lifecycle = aws.StringValue(instance.InstanceLifecycle)
+	if deployInstance.Lifecycle == "" {
+		deployInstance.Lifecycle = "on-demand"
+	}
+
+	tags := []Tag{}
+	for _, tag := range instance.Tags {
+		tags = append(tags, Tag{
+			Key:   aws.StringValue(tag.Key),
+			Value: aws.StringValue(tag.Value),
+		})
+	}
+	deployInstance.Tags = tags
+
+	securityGroups := []SecurityGroup{}
+	for _, group := range instance.SecurityGroups {
+		securityGroups = append(securityGroups, SecurityGroup{
+			GroupId:   aws.StringValue(group.GroupId),
+			GroupName: aws.StringValue(group.GroupName),
+		})
+	}
+	deployInstance.SecurityGroups = securityGroups
+
+	return deployInstance
+}
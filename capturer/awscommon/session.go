@@ -0,0 +1,116 @@
+package awscommon
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// endpointServiceIds maps the service names accepted in the aws.endpoints
+// config section to the endpoint ids the SDK resolves.
+var endpointServiceIds = map[string]string{
+	"autoscaling": endpoints.AutoscalingServiceID,
+	"ecs":         endpoints.EcsServiceID,
+	"ec2":         endpoints.Ec2ServiceID,
+	"elbv2":       endpoints.ElasticloadbalancingServiceID,
+	"cloudwatch":  endpoints.MonitoringServiceID,
+	"iam":         endpoints.IamServiceID,
+	"lambda":      endpoints.LambdaServiceID,
+	"sts":         endpoints.StsServiceID,
+}
+
+// NewSession creates a session for the region, shared by all AWS capturers.
+// Endpoints listed in aws.endpoints replace the real AWS ones, so capturers can
+// run against a local stand-in. Requests of all sessions using the same
+// credentials share one rate limiter.
+func NewSession(viper *viper.Viper, regionName string) (*session.Session, error) {
+	awsId := os.Getenv("awsId")
+	awsSecret := os.Getenv("awsSecret")
+	creds := credentials.NewStaticCredentials(awsId, awsSecret, "")
+	config := &aws.Config{
+		Region: aws.String(regionName),
+	}
+	config = config.WithCredentials(creds)
+
+	resolver, err := newEndpointResolver(viper.GetStringMapString("endpoints"))
+	if err != nil {
+		glog.Errorf("Unable to create endpoint resolver: %s", err)
+		return nil, err
+	}
+	config = config.WithEndpointResolver(resolver)
+
+	if viper.GetBool("disableSSL") {
+		config = config.WithDisableSSL(true)
+	}
+
+	if viper.GetBool("insecureSkipVerify") {
+		config = config.WithHTTPClient(&http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		})
+	}
+
+	retryer, err := newThrottleRetryer(viper)
+	if err != nil {
+		glog.Errorf("Unable to create retryer: %s", err)
+		return nil, err
+	}
+	config = request.WithRetryer(config, retryer)
+
+	sess, err := session.NewSession(config)
+	if err != nil {
+		glog.Errorf("Unable to create session: %s", err)
+		return nil, err
+	}
+
+	if err := installRateLimiter(viper, sess, awsId); err != nil {
+		glog.Errorf("Unable to create rate limiter: %s", err)
+		return nil, err
+	}
+
+	return sess, nil
+}
+
+func newEndpointResolver(overrides map[string]string) (endpoints.Resolver, error) {
+	endpointUrls := make(map[string]string)
+	for service, url := range overrides {
+		if url == "" {
+			continue
+		}
+
+		serviceId, ok := endpointServiceIds[service]
+		if !ok {
+			return nil, errors.New("Unsupported endpoint override for service: " + service)
+		}
+		endpointUrls[serviceId] = url
+	}
+
+	defaultResolver := endpoints.DefaultResolver()
+	if len(endpointUrls) == 0 {
+		return defaultResolver, nil
+	}
+
+	return endpoints.ResolverFunc(func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		if url, ok := endpointUrls[service]; ok {
+			glog.V(2).Infof("Using endpoint override %s for service %s", url, service)
+			return endpoints.ResolvedEndpoint{
+				URL:           url,
+				SigningRegion: region,
+			}, nil
+		}
+
+		return defaultResolver.EndpointFor(service, region, opts...)
+	}), nil
+}
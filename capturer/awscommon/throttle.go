@@ -0,0 +1,172 @@
+package awscommon
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const (
+	defaultRequestsPerSecond = 10
+	defaultBurst             = 20
+	defaultMaxRetries        = 8
+	defaultMinRetryDelay     = 500 * time.Millisecond
+	defaultMaxRetryDelay     = 30 * time.Second
+)
+
+var (
+	rateLimitersMutex sync.Mutex
+	// rateLimiters holds one limiter per account, shared by every capturer's session
+	rateLimiters = make(map[string]*RateLimiter)
+)
+
+// RateLimiter is a token bucket that refills at rate tokens per second up to burst.
+type RateLimiter struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available. Tokens are reserved before waiting,
+// so concurrent callers are served in order.
+func (limiter *RateLimiter) Wait() {
+	limiter.mutex.Lock()
+	now := time.Now()
+	limiter.tokens += now.Sub(limiter.last).Seconds() * limiter.rate
+	if limiter.tokens > limiter.burst {
+		limiter.tokens = limiter.burst
+	}
+	limiter.last = now
+	limiter.tokens--
+
+	var delay time.Duration
+	if limiter.tokens < 0 {
+		delay = time.Duration(-limiter.tokens / limiter.rate * float64(time.Second))
+	}
+	limiter.mutex.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// accountRateLimiter returns the limiter of the account, creating it on first use
+func accountRateLimiter(account string, rate float64, burst int) *RateLimiter {
+	rateLimitersMutex.Lock()
+	defer rateLimitersMutex.Unlock()
+
+	limiter, ok := rateLimiters[account]
+	if !ok {
+		limiter = NewRateLimiter(rate, burst)
+		rateLimiters[account] = limiter
+	}
+
+	return limiter
+}
+
+// newThrottleRetryer reads aws.throttling and returns a retryer backing off
+// exponentially with jitter, the retry delays bound both throttled and other
+// retried requests.
+func newThrottleRetryer(viper *viper.Viper) (request.Retryer, error) {
+	maxRetries := defaultMaxRetries
+	if viper.IsSet("throttling.maxRetries") {
+		maxRetries = viper.GetInt("throttling.maxRetries")
+	}
+
+	minDelay, err := durationOrDefault(viper, "throttling.minRetryDelay", defaultMinRetryDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	maxDelay, err := durationOrDefault(viper, "throttling.maxRetryDelay", defaultMaxRetryDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.DefaultRetryer{
+		NumMaxRetries:    maxRetries,
+		MinRetryDelay:    minDelay,
+		MaxRetryDelay:    maxDelay,
+		MinThrottleDelay: minDelay,
+		MaxThrottleDelay: maxDelay,
+	}, nil
+}
+
+// installRateLimiter makes every request attempt of the session wait on the
+// account's limiter, configured by aws.throttling.
+func installRateLimiter(viper *viper.Viper, sess *session.Session, account string) error {
+	rate := float64(defaultRequestsPerSecond)
+	if viper.IsSet("throttling.requestsPerSecond") {
+		rate = viper.GetFloat64("throttling.requestsPerSecond")
+	}
+
+	burst := defaultBurst
+	if viper.IsSet("throttling.burst") {
+		burst = viper.GetInt("throttling.burst")
+	}
+
+	if rate <= 0 || burst <= 0 {
+		return errors.New("throttling.requestsPerSecond and throttling.burst must be positive")
+	}
+
+	limiter := accountRateLimiter(account, rate, burst)
+	sess.Handlers.Send.PushFront(func(r *request.Request) {
+		limiter.Wait()
+	})
+
+	return nil
+}
+
+func durationOrDefault(viper *viper.Viper, key string, defaultValue time.Duration) (time.Duration, error) {
+	if !viper.IsSet(key) {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(viper.GetString(key))
+	if err != nil {
+		return 0, errors.New("Unable to parse " + key + ": " + err.Error())
+	}
+
+	return duration, nil
+}
+
+// ThrottleCounter counts the throttled request attempts of a session.
+type ThrottleCounter struct {
+	count int64
+}
+
+// NewThrottleCounter counts throttled attempts of every client created from
+// the session afterwards.
+func NewThrottleCounter(sess *session.Session) *ThrottleCounter {
+	counter := &ThrottleCounter{}
+	sess.Handlers.Retry.PushFront(func(r *request.Request) {
+		if r.IsErrorThrottle() {
+			atomic.AddInt64(&counter.count, 1)
+		}
+	})
+
+	return counter
+}
+
+// Reset returns the number of throttled attempts since the last reset
+func (counter *ThrottleCounter) Reset() int64 {
+	return atomic.SwapInt64(&counter.count, 0)
+}
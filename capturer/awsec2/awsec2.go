@@ -0,0 +1,101 @@
+package awsec2
+
+import (
+	"errors"
+
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/hyperpilotio/ingestor/capturer/awscommon"
+	"github.com/hyperpilotio/ingestor/database"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const defaultTableName = "instances"
+
+type Inventory struct {
+	ID                bson.ObjectId        `json:"id" bson:"_id,omitempty"`
+	Region            string               `json:"Region" bson:"Region"`
+	Instances         []awscommon.Instance `json:"Instances" bson:"Instances"`
+	ThrottledRequests int64                `json:"ThrottledRequests" bson:"ThrottledRequests"`
+}
+
+type AWSEC2Capturer struct {
+	Region    string
+	Sess      *session.Session
+	DB        *database.MongoDB
+	Throttles *awscommon.ThrottleCounter
+}
+
+func NewCapturer(config *viper.Viper, region string) (*AWSEC2Capturer, error) {
+	db, dbErr := database.NewDB(config)
+	if dbErr != nil {
+		return nil, dbErr
+	}
+
+	db.TableName = config.GetString("ec2.tableName")
+	if db.TableName == "" {
+		db.TableName = defaultTableName
+	}
+
+	session, err := awscommon.NewSession(config, region)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AWSEC2Capturer{
+		Region:    region,
+		Sess:      session,
+		DB:        db,
+		Throttles: awscommon.NewThrottleCounter(session),
+	}, nil
+}
+
+func (capturer AWSEC2Capturer) Capture() error {
+	inventory, err := capturer.GetInstances()
+	if err != nil {
+		return errors.New("Unable to get instances info: " + err.Error())
+	}
+
+	selector := bson.M{"Region": capturer.Region}
+	if err := capturer.DB.Upsert(selector, *inventory); err != nil {
+		return errors.New("Unable to write instances info: " + err.Error())
+	}
+
+	return nil
+}
+
+func (capturer AWSEC2Capturer) GetInstances() (*Inventory, error) {
+	glog.V(1).Infof("GetInstances for region: %s", capturer.Region)
+
+	ec2Svc := ec2.New(capturer.Sess)
+
+	instances := []awscommon.Instance{}
+	err := ec2Svc.DescribeInstancesPages(&ec2.DescribeInstancesInput{}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				instances = append(instances, *awscommon.NewInstance(instance))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.New("Unable to describe instances: " + err.Error())
+	}
+
+	inventory := &Inventory{
+		Region:    capturer.Region,
+		Instances: instances,
+	}
+
+	inventory.ThrottledRequests = capturer.Throttles.Reset()
+	if inventory.ThrottledRequests > 0 {
+		glog.Warningf("%d requests throttled while capturing instances of region %s", inventory.ThrottledRequests, capturer.Region)
+	}
+
+	return inventory, nil
+}
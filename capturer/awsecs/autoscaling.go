@@ -0,0 +1,131 @@
+package awsecs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// autoScalingGroupTag is set by Auto Scaling on every instance it launches
+const autoScalingGroupTag = "aws:autoscaling:groupName"
+
+// describeAutoScalingGroupsBatchSize is the number of group names sent per DescribeAutoScalingGroups call
+const describeAutoScalingGroupsBatchSize = 50
+
+// maxScalingActivities caps how many of the most recent scaling activities are kept per group
+const maxScalingActivities = 10
+
+type LaunchTemplate struct {
+	LaunchTemplateId   string `json:"LaunchTemplateId" bson:"LaunchTemplateId"`
+	LaunchTemplateName string `json:"LaunchTemplateName" bson:"LaunchTemplateName"`
+	Version            string `json:"Version" bson:"Version"`
+}
+
+type ScalingActivity struct {
+	ActivityId  string    `json:"ActivityId" bson:"ActivityId"`
+	Description string    `json:"Description" bson:"Description"`
+	Cause       string    `json:"Cause" bson:"Cause"`
+	StatusCode  string    `json:"StatusCode" bson:"StatusCode"`
+	Progress    int64     `json:"Progress" bson:"Progress"`
+	StartTime   time.Time `json:"StartTime" bson:"StartTime"`
+	EndTime     time.Time `json:"EndTime" bson:"EndTime"`
+}
+
+type AutoScalingGroup struct {
+	AutoScalingGroupName             string            `json:"AutoScalingGroupName" bson:"AutoScalingGroupName"`
+	AutoScalingGroupArn              string            `json:"AutoScalingGroupArn" bson:"AutoScalingGroupArn"`
+	MinSize                          int64             `json:"MinSize" bson:"MinSize"`
+	MaxSize                          int64             `json:"MaxSize" bson:"MaxSize"`
+	DesiredCapacity                  int64             `json:"DesiredCapacity" bson:"DesiredCapacity"`
+	LaunchConfigurationName          string            `json:"LaunchConfigurationName" bson:"LaunchConfigurationName"`
+	LaunchTemplate                   *LaunchTemplate   `json:"LaunchTemplate" bson:"LaunchTemplate"`
+	NewInstancesProtectedFromScaleIn bool              `json:"NewInstancesProtectedFromScaleIn" bson:"NewInstancesProtectedFromScaleIn"`
+	ProtectedInstanceIds             []string          `json:"ProtectedInstanceIds" bson:"ProtectedInstanceIds"`
+	Activities                       []ScalingActivity `json:"Activities" bson:"Activities"`
+}
+
+// describeAutoScalingGroups describes the named groups with their most recent
+// scaling activities.
+func describeAutoScalingGroups(autoscalingSvc *autoscaling.AutoScaling, groupNames []*string) ([]AutoScalingGroup, error) {
+	groups := []AutoScalingGroup{}
+	for start := 0; start < len(groupNames); start += describeAutoScalingGroupsBatchSize {
+		end := start + describeAutoScalingGroupsBatchSize
+		if end > len(groupNames) {
+			end = len(groupNames)
+		}
+
+		describeGroupsInput := &autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: groupNames[start:end],
+		}
+		describeGroupsOutput, err := autoscalingSvc.DescribeAutoScalingGroups(describeGroupsInput)
+		if err != nil {
+			return nil, errors.New("Unable to describe auto scaling groups: " + err.Error())
+		}
+
+		for _, group := range describeGroupsOutput.AutoScalingGroups {
+			autoScalingGroup := newAutoScalingGroup(group)
+
+			describeActivitiesInput := &autoscaling.DescribeScalingActivitiesInput{
+				AutoScalingGroupName: group.AutoScalingGroupName,
+				MaxRecords:           aws.Int64(maxScalingActivities),
+			}
+			describeActivitiesOutput, err := autoscalingSvc.DescribeScalingActivities(describeActivitiesInput)
+			if err != nil {
+				return nil, errors.New("Unable to describe scaling activities of " +
+					autoScalingGroup.AutoScalingGroupName + ": " + err.Error())
+			}
+
+			for _, activity := range describeActivitiesOutput.Activities {
+				autoScalingGroup.Activities = append(autoScalingGroup.Activities, ScalingActivity{
+					ActivityId:  aws.StringValue(activity.ActivityId),
+					Description: aws.StringValue(activity.Description),
+					Cause:       aws.StringValue(activity.Cause),
+					StatusCode:  aws.StringValue(activity.StatusCode),
+					Progress:    aws.Int64Value(activity.Progress),
+					StartTime:   aws.TimeValue(activity.StartTime),
+					EndTime:     aws.TimeValue(activity.EndTime),
+				})
+			}
+			groups = append(groups, *autoScalingGroup)
+		}
+	}
+
+	return groups, nil
+}
+
+func newAutoScalingGroup(group *autoscaling.Group) *AutoScalingGroup {
+	autoScalingGroup := &AutoScalingGroup{}
+	autoScalingGroup.AutoScalingGroupName = aws.StringValue(group.AutoScalingGroupName)
+	autoScalingGroup.AutoScalingGroupArn = aws.StringValue(group.AutoScalingGroupARN)
+	autoScalingGroup.MinSize = aws.Int64Value(group.MinSize)
+	autoScalingGroup.MaxSize = aws.Int64Value(group.MaxSize)
+	autoScalingGroup.DesiredCapacity = aws.Int64Value(group.DesiredCapacity)
+	autoScalingGroup.LaunchConfigurationName = aws.StringValue(group.LaunchConfigurationName)
+	autoScalingGroup.NewInstancesProtectedFromScaleIn = aws.BoolValue(group.NewInstancesProtectedFromScaleIn)
+
+	// groups with a mixed instances policy carry their template inside the policy
+	launchTemplate := group.LaunchTemplate
+	if launchTemplate == nil && group.MixedInstancesPolicy != nil && group.MixedInstancesPolicy.LaunchTemplate != nil {
+		launchTemplate = group.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+	if launchTemplate != nil {
+		autoScalingGroup.LaunchTemplate = &LaunchTemplate{
+			LaunchTemplateId:   aws.StringValue(launchTemplate.LaunchTemplateId),
+			LaunchTemplateName: aws.StringValue(launchTemplate.LaunchTemplateName),
+			Version:            aws.StringValue(launchTemplate.Version),
+		}
+	}
+
+	protectedInstanceIds := []string{}
+	for _, instance := range group.Instances {
+		if aws.BoolValue(instance.ProtectedFromScaleIn) {
+			protectedInstanceIds = append(protectedInstanceIds, aws.StringValue(instance.InstanceId))
+		}
+	}
+	autoScalingGroup.ProtectedInstanceIds = protectedInstanceIds
+	autoScalingGroup.Activities = []ScalingActivity{}
+
+	return autoScalingGroup
+}
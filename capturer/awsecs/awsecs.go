@@ -2,86 +2,218 @@ package awsecs
 
 import (
 	"errors"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/spf13/viper"
 	"gopkg.in/mgo.v2/bson"
 
+	"github.com/hyperpilotio/ingestor/capturer/awscommon"
 	"github.com/hyperpilotio/ingestor/database"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 
+	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/iam"
 )
 
-type Instance struct {
-	InstanceId   string    `json:"InstanceId" bson:"InstanceId"`
-	InstanceType string    `json:"InstanceType" bson:"InstanceType"`
-	LaunchTime   time.Time `json:"LaunchTime" bson:"LaunchTime"`
+type NetworkBinding struct {
+	BindIP        string `json:"BindIP" bson:"BindIP"`
+	ContainerPort int64  `json:"ContainerPort" bson:"ContainerPort"`
+	HostPort      int64  `json:"HostPort" bson:"HostPort"`
+	Protocol      string `json:"Protocol" bson:"Protocol"`
 }
 
 type Container struct {
-	ContainerArn string `json:"ContainerArn" bson:"ContainerArn"`
-	Name         string `json:"Name" bson:"Name"`
+	ContainerArn    string           `json:"ContainerArn" bson:"ContainerArn"`
+	Name            string           `json:"Name" bson:"Name"`
+	LastStatus      string           `json:"LastStatus" bson:"LastStatus"`
+	HealthStatus    string           `json:"HealthStatus" bson:"HealthStatus"`
+	ExitCode        *int64           `json:"ExitCode" bson:"ExitCode"`
+	Reason          string           `json:"Reason" bson:"Reason"`
+	ImageDigest     string           `json:"ImageDigest" bson:"ImageDigest"`
+	NetworkBindings []NetworkBinding `json:"NetworkBindings" bson:"NetworkBindings"`
+}
+
+type TaskNetworkInterface struct {
+	NetworkInterfaceId string `json:"NetworkInterfaceId" bson:"NetworkInterfaceId"`
+	PrivateIPv4Address string `json:"PrivateIPv4Address" bson:"PrivateIPv4Address"`
+	SubnetId           string `json:"SubnetId" bson:"SubnetId"`
+	MacAddress         string `json:"MacAddress" bson:"MacAddress"`
 }
 
 type Task struct {
-	Containers        []Container `json:"Containers" bson:"Containers"`
-	TaskArn           string      `json:"TaskArn" bson:"TaskArn"`
-	TaskDefinitionArn string      `json:"TaskDefinitionArn" bson:"TaskDefinitionArn"`
+	Containers           []Container            `json:"Containers" bson:"Containers"`
+	TaskArn              string                 `json:"TaskArn" bson:"TaskArn"`
+	TaskDefinitionArn    string                 `json:"TaskDefinitionArn" bson:"TaskDefinitionArn"`
+	ContainerInstanceArn string                 `json:"ContainerInstanceArn" bson:"ContainerInstanceArn"`
+	LaunchType           string                 `json:"LaunchType" bson:"LaunchType"`
+	PlatformVersion      string                 `json:"PlatformVersion" bson:"PlatformVersion"`
+	Cpu                  string                 `json:"Cpu" bson:"Cpu"`
+	Memory               string                 `json:"Memory" bson:"Memory"`
+	NetworkInterfaces    []TaskNetworkInterface `json:"NetworkInterfaces" bson:"NetworkInterfaces"`
+	LastStatus           string                 `json:"LastStatus" bson:"LastStatus"`
+	DesiredStatus        string                 `json:"DesiredStatus" bson:"DesiredStatus"`
+	HealthStatus         string                 `json:"HealthStatus" bson:"HealthStatus"`
+	StartedBy            string                 `json:"StartedBy" bson:"StartedBy"`
+	Group                string                 `json:"Group" bson:"Group"`
+	StoppedReason        string                 `json:"StoppedReason" bson:"StoppedReason"`
+	CreatedAt            time.Time              `json:"CreatedAt" bson:"CreatedAt"`
+	StartedAt            time.Time              `json:"StartedAt" bson:"StartedAt"`
+	StoppedAt            time.Time              `json:"StoppedAt" bson:"StoppedAt"`
+	TaskRoleArn          string                 `json:"TaskRoleArn" bson:"TaskRoleArn"`
+	ExecutionRoleArn     string                 `json:"ExecutionRoleArn" bson:"ExecutionRoleArn"`
+	TargetHealth         []TaskTargetHealth     `json:"TargetHealth" bson:"TargetHealth"`
+}
+
+type NodeResources struct {
+	CPU      int64    `json:"CPU" bson:"CPU"`
+	Memory   int64    `json:"Memory" bson:"Memory"`
+	Ports    []string `json:"Ports" bson:"Ports"`
+	UDPPorts []string `json:"UDPPorts" bson:"UDPPorts"`
+}
+
+type NodeAttribute struct {
+	Name  string `json:"Name" bson:"Name"`
+	Value string `json:"Value" bson:"Value"`
 }
 
 type NodeInfo struct {
-	Instance      Instance `json:"Instance" bson:"Instance"`
-	Arn           string   `json:"Arn" bson:"Arn"`
-	PublicDnsName string   `json:"PublicDnsName" bson:"PublicDnsName"`
-	Tasks         []Task   `json:"Tasks" bson:"Tasks"`
+	Instance            awscommon.Instance `json:"Instance" bson:"Instance"`
+	Arn                 string             `json:"Arn" bson:"Arn"`
+	PublicDnsName       string             `json:"PublicDnsName" bson:"PublicDnsName"`
+	Status              string             `json:"Status" bson:"Status"`
+	AgentConnected      bool               `json:"AgentConnected" bson:"AgentConnected"`
+	AgentVersion        string             `json:"AgentVersion" bson:"AgentVersion"`
+	DockerVersion       string             `json:"DockerVersion" bson:"DockerVersion"`
+	RunningTasksCount   int64              `json:"RunningTasksCount" bson:"RunningTasksCount"`
+	PendingTasksCount   int64              `json:"PendingTasksCount" bson:"PendingTasksCount"`
+	RegisteredResources NodeResources      `json:"RegisteredResources" bson:"RegisteredResources"`
+	RemainingResources  NodeResources      `json:"RemainingResources" bson:"RemainingResources"`
+	Attributes          []NodeAttribute    `json:"Attributes" bson:"Attributes"`
+	AutoScalingGroup    string             `json:"AutoScalingGroup" bson:"AutoScalingGroup"`
+	Tasks               []Task             `json:"Tasks" bson:"Tasks"`
+}
+
+type DeploymentConfiguration struct {
+	MaximumPercent        int64 `json:"MaximumPercent" bson:"MaximumPercent"`
+	MinimumHealthyPercent int64 `json:"MinimumHealthyPercent" bson:"MinimumHealthyPercent"`
+}
+
+type ServiceDeployment struct {
+	Id              string    `json:"Id" bson:"Id"`
+	Status          string    `json:"Status" bson:"Status"`
+	TaskDefinition  string    `json:"TaskDefinition" bson:"TaskDefinition"`
+	LaunchType      string    `json:"LaunchType" bson:"LaunchType"`
+	PlatformVersion string    `json:"PlatformVersion" bson:"PlatformVersion"`
+	DesiredCount    int64     `json:"DesiredCount" bson:"DesiredCount"`
+	RunningCount    int64     `json:"RunningCount" bson:"RunningCount"`
+	PendingCount    int64     `json:"PendingCount" bson:"PendingCount"`
+	CreatedAt       time.Time `json:"CreatedAt" bson:"CreatedAt"`
+	UpdatedAt       time.Time `json:"UpdatedAt" bson:"UpdatedAt"`
+}
+
+type ServiceLoadBalancer struct {
+	LoadBalancerName string   `json:"LoadBalancerName" bson:"LoadBalancerName"`
+	TargetGroupArn   string   `json:"TargetGroupArn" bson:"TargetGroupArn"`
+	ContainerName    string   `json:"ContainerName" bson:"ContainerName"`
+	ContainerPort    int64    `json:"ContainerPort" bson:"ContainerPort"`
+	LoadBalancerArns []string `json:"LoadBalancerArns" bson:"LoadBalancerArns"`
+}
+
+type PlacementConstraint struct {
+	Type       string `json:"Type" bson:"Type"`
+	Expression string `json:"Expression" bson:"Expression"`
+}
+
+type PlacementStrategy struct {
+	Type  string `json:"Type" bson:"Type"`
+	Field string `json:"Field" bson:"Field"`
+}
+
+type ServiceEvent struct {
+	Id        string    `json:"Id" bson:"Id"`
+	Message   string    `json:"Message" bson:"Message"`
+	CreatedAt time.Time `json:"CreatedAt" bson:"CreatedAt"`
 }
 
 type Service struct {
-	ServiceName    string `json:"ServiceName" bson:"ServiceName"`
-	ServiceArn     string `json:"ServiceArn" bson:"ServiceArn"`
-	TaskDefinition string `json:"TaskDefinition" bson:"TaskDefinition"`
+	ServiceName             string                  `json:"ServiceName" bson:"ServiceName"`
+	ServiceArn              string                  `json:"ServiceArn" bson:"ServiceArn"`
+	TaskDefinition          string                  `json:"TaskDefinition" bson:"TaskDefinition"`
+	Status                  string                  `json:"Status" bson:"Status"`
+	LaunchType              string                  `json:"LaunchType" bson:"LaunchType"`
+	DesiredCount            int64                   `json:"DesiredCount" bson:"DesiredCount"`
+	RunningCount            int64                   `json:"RunningCount" bson:"RunningCount"`
+	PendingCount            int64                   `json:"PendingCount" bson:"PendingCount"`
+	DeploymentConfiguration DeploymentConfiguration `json:"DeploymentConfiguration" bson:"DeploymentConfiguration"`
+	Deployments             []ServiceDeployment     `json:"Deployments" bson:"Deployments"`
+	LoadBalancers           []ServiceLoadBalancer   `json:"LoadBalancers" bson:"LoadBalancers"`
+	PlacementConstraints    []PlacementConstraint   `json:"PlacementConstraints" bson:"PlacementConstraints"`
+	PlacementStrategy       []PlacementStrategy     `json:"PlacementStrategy" bson:"PlacementStrategy"`
+	Events                  []ServiceEvent          `json:"Events" bson:"Events"`
+	TaskRoleArn             string                  `json:"TaskRoleArn" bson:"TaskRoleArn"`
+	ExecutionRoleArn        string                  `json:"ExecutionRoleArn" bson:"ExecutionRoleArn"`
 }
 
 type Cluster struct {
-	ClusterName string     `json:"ClusterName" bson:"ClusterName"`
-	NodeInfos   []NodeInfo `json:"NodeInfos" bson:"NodeInfos"`
-	Services    []Service  `json:"Services" bson:"Services"`
+	ClusterName                       string                         `json:"ClusterName" bson:"ClusterName"`
+	ClusterArn                        string                         `json:"ClusterArn" bson:"ClusterArn"`
+	Status                            string                         `json:"Status" bson:"Status"`
+	RegisteredContainerInstancesCount int64                          `json:"RegisteredContainerInstancesCount" bson:"RegisteredContainerInstancesCount"`
+	RunningTasksCount                 int64                          `json:"RunningTasksCount" bson:"RunningTasksCount"`
+	PendingTasksCount                 int64                          `json:"PendingTasksCount" bson:"PendingTasksCount"`
+	ActiveServicesCount               int64                          `json:"ActiveServicesCount" bson:"ActiveServicesCount"`
+	ContainerInsights                 string                         `json:"ContainerInsights" bson:"ContainerInsights"`
+	CapacityProviders                 []CapacityProvider             `json:"CapacityProviders" bson:"CapacityProviders"`
+	DefaultCapacityProviderStrategy   []CapacityProviderStrategyItem `json:"DefaultCapacityProviderStrategy" bson:"DefaultCapacityProviderStrategy"`
+	Tags                              []awscommon.Tag                `json:"Tags" bson:"Tags"`
+	NodeInfos                         []NodeInfo                     `json:"NodeInfos" bson:"NodeInfos"`
+	FargateTasks                      []Task                         `json:"FargateTasks" bson:"FargateTasks"`
+	Services                          []Service                      `json:"Services" bson:"Services"`
+	AutoScalingGroups                 []AutoScalingGroup             `json:"AutoScalingGroups" bson:"AutoScalingGroups"`
+	Roles                             []IAMRole                      `json:"Roles" bson:"Roles"`
+	LoadBalancers                     []LoadBalancer                 `json:"LoadBalancers" bson:"LoadBalancers"`
+	TargetGroups                      []TargetGroup                  `json:"TargetGroups" bson:"TargetGroups"`
 }
 
-type Deployments struct {
-	ID       bson.ObjectId `json:"id" bson:"_id,omitempty"`
-	Region   string        `json:"Region" bson:"Region"`
-	Clusters []Cluster     `json:"Clusters" bson:"Clusters"`
-}
+// maxServiceEvents caps how many of the most recent service events are kept,
+// ECS returns up to the last 100 events for every service.
+const maxServiceEvents = 20
 
-func createSessionByRegion(viper *viper.Viper, regionName string) (*session.Session, error) {
-	awsId := os.Getenv("awsId")
-	awsSecret := os.Getenv("awsSecret")
-	creds := credentials.NewStaticCredentials(awsId, awsSecret, "")
-	config := &aws.Config{
-		Region: aws.String(regionName),
-	}
-	config = config.WithCredentials(creds)
-	sess, err := session.NewSession(config)
-	if err != nil {
-		glog.Errorf("Unable to create session: %s", err)
-		return nil, err
-	}
+// describeClustersBatchSize is the maximum number of clusters DescribeClusters accepts
+const describeClustersBatchSize = 100
+
+// describeTasksBatchSize is the maximum number of tasks DescribeTasks accepts
+const describeTasksBatchSize = 100
+
+// describeServicesBatchSize is the maximum number of services DescribeServices accepts
+const describeServicesBatchSize = 10
+
+// describeContainerInstancesBatchSize is the maximum number of container instances DescribeContainerInstances accepts
+const describeContainerInstancesBatchSize = 100
 
-	return sess, nil
+// describeInstancesBatchSize is the number of instance ids sent per DescribeInstances filter
+const describeInstancesBatchSize = 100
+
+type Deployments struct {
+	ID                bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Region            string        `json:"Region" bson:"Region"`
+	Clusters          []Cluster     `json:"Clusters" bson:"Clusters"`
+	ThrottledRequests int64         `json:"ThrottledRequests" bson:"ThrottledRequests"`
 }
 
 type AWSECSCapturer struct {
-	Region string
-	Sess   *session.Session
-	DB     *database.MongoDB
+	Region    string
+	Sess      *session.Session
+	DB        *database.MongoDB
+	Filter    *ClusterFilter
+	Throttles *awscommon.ThrottleCounter
 }
 
 func NewCapturer(config *viper.Viper, region string) (*AWSECSCapturer, error) {
@@ -90,13 +222,20 @@ func NewCapturer(config *viper.Viper, region string) (*AWSECSCapturer, error) {
 		return nil, dbErr
 	}
 
-	if session, err := createSessionByRegion(config, region); err != nil {
+	filter, err := NewClusterFilter(config, region)
+	if err != nil {
+		return nil, errors.New("Unable to create cluster filter: " + err.Error())
+	}
+
+	if session, err := awscommon.NewSession(config, region); err != nil {
 		return nil, err
 	} else {
 		return &AWSECSCapturer{
-			Region: region,
-			Sess:   session,
-			DB:     db,
+			Region:    region,
+			Sess:      session,
+			DB:        db,
+			Filter:    filter,
+			Throttles: awscommon.NewThrottleCounter(session),
 		}, nil
 	}
 }
@@ -118,128 +257,523 @@ func (capturer AWSECSCapturer) GetClusters() (*Deployments, error) {
 
 	ecsSvc := ecs.New(capturer.Sess)
 	ec2Svc := ec2.New(capturer.Sess)
+	autoscalingSvc := autoscaling.New(capturer.Sess)
+	elbSvc := elbv2.New(capturer.Sess)
+	roles := newRoleResolver(ecsSvc, iam.New(capturer.Sess))
+	capacityProviders := newCapacityProviderCache(ecsSvc)
 
-	// find clusters on region
-	listClustersOutput, listClustersErr := ecsSvc.ListClusters(nil)
-	if listClustersErr != nil {
-		return nil, errors.New("Unable to find any clusters: " + listClustersErr.Error())
-	}
-
-	if len(listClustersOutput.ClusterArns) == 0 {
-		return nil, errors.New("Unable to find any clusters: ClusterArns size is zero")
-	}
-
-	// use clusterArns get region's clusters information
-	describeClustersInput := &ecs.DescribeClustersInput{
-		Clusters: listClustersOutput.ClusterArns,
+	clusters, err := DescribeClusters(ecsSvc, capturer.Filter, capturer.Region)
+	if err != nil {
+		return nil, err
 	}
-	describeClustersOutput, _ := ecsSvc.DescribeClusters(describeClustersInput)
 
 	deployments := &Deployments{Region: capturer.Region}
 	deployClusters := []Cluster{}
-	for _, cluster := range describeClustersOutput.Clusters {
+	for _, cluster := range clusters {
 		clusterName := *cluster.ClusterName
 		deployCluster := &Cluster{}
 		deployCluster.ClusterName = clusterName
+		if err := setClusterSettings(deployCluster, cluster, capacityProviders); err != nil {
+			return nil, errors.New("Unable to get settings of cluster " + clusterName + ": " + err.Error())
+		}
 
-		// use clusterName get instancess arns of Container
-		listInstancesInput := &ecs.ListContainerInstancesInput{
-			Cluster: aws.String(clusterName),
+		// list tasks on the cluster regardless of launch type, EC2 tasks are
+		// linked to their container instance and Fargate tasks kept on the cluster
+		tasks, err := describeClusterTasks(ecsSvc, clusterName)
+		if err != nil {
+			return nil, errors.New("Unable to get tasks of cluster " + clusterName + ": " + err.Error())
 		}
-		listContainerInstancesOutput, _ := ecsSvc.ListContainerInstances(listInstancesInput)
 
-		// use containerInstanceArns get ContainerInstances
-		ecsDescribeInstancesInput := &ecs.DescribeContainerInstancesInput{
-			Cluster:            aws.String(clusterName),
-			ContainerInstances: listContainerInstancesOutput.ContainerInstanceArns,
+		instanceTasks := make(map[string][]Task)
+		fargateTasks := []Task{}
+		for _, task := range tasks {
+			clusterTask := newTask(task)
+			if instanceArn := clusterTask.ContainerInstanceArn; instanceArn == "" {
+				fargateTasks = append(fargateTasks, *clusterTask)
+			} else {
+				instanceTasks[instanceArn] = append(instanceTasks[instanceArn], *clusterTask)
+			}
+		}
+		deployCluster.FargateTasks = fargateTasks
+
+		containerInstances, err := describeContainerInstances(ecsSvc, clusterName)
+		if err != nil {
+			return nil, errors.New("Unable to get container instances of cluster " + clusterName + ": " + err.Error())
+		}
+
+		// use Ec2InstanceIds get instances information in batches
+		ec2InstanceIds := []*string{}
+		for _, containerInstance := range containerInstances {
+			ec2InstanceIds = append(ec2InstanceIds, containerInstance.Ec2InstanceId)
+		}
+		ec2Instances, err := describeEC2Instances(ec2Svc, ec2InstanceIds)
+		if err != nil {
+			return nil, errors.New("Unable to get instances of cluster " + clusterName + ": " + err.Error())
 		}
-		ecsDescribeInstancesOutput, _ := ecsSvc.DescribeContainerInstances(ecsDescribeInstancesInput)
 
-		// use Ec2InstanceId get instance information
 		nodeInfos := []NodeInfo{}
-		for _, containerInstance := range ecsDescribeInstancesOutput.ContainerInstances {
+		autoScalingGroupNames := []string{}
+		for _, containerInstance := range containerInstances {
 			ec2InstanceId := *containerInstance.Ec2InstanceId
 			containerInstanceArn := *containerInstance.ContainerInstanceArn
-			ec2DescribeInstancesInput := &ec2.DescribeInstancesInput{
-				InstanceIds: []*string{
-					aws.String(ec2InstanceId),
-				},
-			}
-			ec2DescribeInstanceOutput, _ := ec2Svc.DescribeInstances(ec2DescribeInstancesInput)
-
-			reservation := ec2DescribeInstanceOutput.Reservations[0]
-			instance := reservation.Instances[0]
-
-			deployInstance := &Instance{}
-			deployInstance.InstanceId = *instance.InstanceId
-			deployInstance.InstanceType = *instance.InstanceType
-			deployInstance.LaunchTime = *instance.LaunchTime
 
 			nodeInfo := &NodeInfo{}
-			nodeInfo.PublicDnsName = *instance.PublicDnsName
 			nodeInfo.Arn = containerInstanceArn
-			nodeInfo.Instance = *deployInstance
-
-			// use clusterName get TaskArns
-			listTasksInput := &ecs.ListTasksInput{
-				Cluster:           aws.String(clusterName),
-				ContainerInstance: aws.String(containerInstanceArn),
+			if instance, ok := ec2Instances[ec2InstanceId]; ok {
+				nodeInfo.PublicDnsName = aws.StringValue(instance.PublicDnsName)
+				nodeInfo.Instance = *awscommon.NewInstance(instance)
+				for _, tag := range nodeInfo.Instance.Tags {
+					if tag.Key == autoScalingGroupTag {
+						nodeInfo.AutoScalingGroup = tag.Value
+					}
+				}
+			} else {
+				glog.Warningf("Unable to find EC2 instance %s of container instance %s", ec2InstanceId, containerInstanceArn)
+				nodeInfo.Instance.InstanceId = ec2InstanceId
 			}
-			listTasksOutput, _ := ecsSvc.ListTasks(listTasksInput)
+			setContainerInstanceInfo(nodeInfo, containerInstance)
 
-			// use TaskArns get task information
-			describeTasksInput := &ecs.DescribeTasksInput{
-				Tasks:   listTasksOutput.TaskArns,
-				Cluster: aws.String(clusterName),
+			nodeInfo.Tasks = instanceTasks[containerInstanceArn]
+			if nodeInfo.Tasks == nil {
+				nodeInfo.Tasks = []Task{}
 			}
-			describeTasksOutput, _ := ecsSvc.DescribeTasks(describeTasksInput)
-
-			clusterTasks := []Task{}
-			for _, task := range describeTasksOutput.Tasks {
-				clusterTask := &Task{}
-				clusterTask.TaskArn = *task.TaskArn
-				clusterTask.TaskDefinitionArn = *task.TaskDefinitionArn
-
-				taskContainers := []Container{}
-				for _, container := range task.Containers {
-					taskContainer := &Container{}
-					taskContainer.ContainerArn = *container.ContainerArn
-					taskContainer.Name = *container.Name
-					taskContainers = append(taskContainers, *taskContainer)
-				}
-				clusterTask.Containers = taskContainers
-				clusterTasks = append(clusterTasks, *clusterTask)
-			}
-			nodeInfo.Tasks = clusterTasks
 			nodeInfos = append(nodeInfos, *nodeInfo)
+
+			if nodeInfo.AutoScalingGroup != "" && !containsString(autoScalingGroupNames, nodeInfo.AutoScalingGroup) {
+				autoScalingGroupNames = append(autoScalingGroupNames, nodeInfo.AutoScalingGroup)
+			}
 		}
 		deployCluster.NodeInfos = nodeInfos
 
-		// use clusterName get ServiceArns
-		listServicesInput := &ecs.ListServicesInput{
-			Cluster: aws.String(clusterName),
+		autoScalingGroups, err := describeAutoScalingGroups(autoscalingSvc, aws.StringSlice(autoScalingGroupNames))
+		if err != nil {
+			return nil, errors.New("Unable to get auto scaling groups of cluster " + clusterName + ": " + err.Error())
 		}
-		listServicesOutput, _ := ecsSvc.ListServices(listServicesInput)
+		deployCluster.AutoScalingGroups = autoScalingGroups
 
-		// use ServiceArns get service information
-		describeServicesInput := &ecs.DescribeServicesInput{
-			Services: listServicesOutput.ServiceArns,
-			Cluster:  aws.String(clusterName),
+		services, err := describeClusterServices(ecsSvc, clusterName)
+		if err != nil {
+			return nil, errors.New("Unable to get services of cluster " + clusterName + ": " + err.Error())
 		}
-		describeServicesOutput, _ := ecsSvc.DescribeServices(describeServicesInput)
 
 		clusterServices := []Service{}
-		for _, service := range describeServicesOutput.Services {
-			clusterService := &Service{}
-			clusterService.ServiceArn = *service.ServiceArn
-			clusterService.ServiceName = *service.ServiceName
-			clusterService.TaskDefinition = *service.TaskDefinition
-			clusterServices = append(clusterServices, *clusterService)
+		for _, service := range services {
+			clusterServices = append(clusterServices, *newService(service))
 		}
 		deployCluster.Services = clusterServices
+
+		if err := roles.resolveCluster(deployCluster); err != nil {
+			return nil, errors.New("Unable to resolve roles of cluster " + clusterName + ": " + err.Error())
+		}
+
+		if err := describeLoadBalancers(elbSvc, deployCluster); err != nil {
+			return nil, errors.New("Unable to get load balancers of cluster " + clusterName + ": " + err.Error())
+		}
 		deployClusters = append(deployClusters, *deployCluster)
 	}
 	deployments.Clusters = deployClusters
 
+	deployments.ThrottledRequests = capturer.Throttles.Reset()
+	if deployments.ThrottledRequests > 0 {
+		glog.Warningf("%d requests throttled while capturing region %s", deployments.ThrottledRequests, capturer.Region)
+	}
+
 	return deployments, nil
 }
+
+func setClusterSettings(deployCluster *Cluster, cluster *ecs.Cluster, capacityProviders *capacityProviderCache) error {
+	deployCluster.ClusterArn = aws.StringValue(cluster.ClusterArn)
+	deployCluster.Status = aws.StringValue(cluster.Status)
+	deployCluster.RegisteredContainerInstancesCount = aws.Int64Value(cluster.RegisteredContainerInstancesCount)
+	deployCluster.RunningTasksCount = aws.Int64Value(cluster.RunningTasksCount)
+	deployCluster.PendingTasksCount = aws.Int64Value(cluster.PendingTasksCount)
+	deployCluster.ActiveServicesCount = aws.Int64Value(cluster.ActiveServicesCount)
+
+	for _, setting := range cluster.Settings {
+		if aws.StringValue(setting.Name) == ecs.ClusterSettingNameContainerInsights {
+			deployCluster.ContainerInsights = aws.StringValue(setting.Value)
+		}
+	}
+
+	providers, err := capacityProviders.describe(aws.StringValueSlice(cluster.CapacityProviders))
+	if err != nil {
+		return err
+	}
+	deployCluster.CapacityProviders = providers
+
+	strategy := []CapacityProviderStrategyItem{}
+	for _, item := range cluster.DefaultCapacityProviderStrategy {
+		strategy = append(strategy, CapacityProviderStrategyItem{
+			CapacityProvider: aws.StringValue(item.CapacityProvider),
+			Weight:           aws.Int64Value(item.Weight),
+			Base:             aws.Int64Value(item.Base),
+		})
+	}
+	deployCluster.DefaultCapacityProviderStrategy = strategy
+
+	tags := []awscommon.Tag{}
+	for _, tag := range cluster.Tags {
+		tags = append(tags, awscommon.Tag{
+			Key:   aws.StringValue(tag.Key),
+			Value: aws.StringValue(tag.Value),
+		})
+	}
+	deployCluster.Tags = tags
+
+	return nil
+}
+
+// describeClusterTasks lists every task of the cluster, including Fargate tasks
+// that don't run on a container instance, and describes them in batches.
+func describeClusterTasks(ecsSvc *ecs.ECS, clusterName string) ([]*ecs.Task, error) {
+	taskArns := []*string{}
+	listTasksInput := &ecs.ListTasksInput{
+		Cluster: aws.String(clusterName),
+	}
+	err := ecsSvc.ListTasksPages(listTasksInput, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+		taskArns = append(taskArns, page.TaskArns...)
+		return true
+	})
+	if err != nil {
+		return nil, errors.New("Unable to list tasks: " + err.Error())
+	}
+
+	tasks := []*ecs.Task{}
+	for start := 0; start < len(taskArns); start += describeTasksBatchSize {
+		end := start + describeTasksBatchSize
+		if end > len(taskArns) {
+			end = len(taskArns)
+		}
+
+		describeTasksInput := &ecs.DescribeTasksInput{
+			Tasks:   taskArns[start:end],
+			Cluster: aws.String(clusterName),
+		}
+		describeTasksOutput, err := ecsSvc.DescribeTasks(describeTasksInput)
+		if err != nil {
+			return nil, errors.New("Unable to describe tasks: " + err.Error())
+		}
+		tasks = append(tasks, describeTasksOutput.Tasks...)
+	}
+
+	return tasks, nil
+}
+
+// describeClusterServices lists every service of the cluster and describes
+// them in batches.
+func describeClusterServices(ecsSvc *ecs.ECS, clusterName string) ([]*ecs.Service, error) {
+	serviceArns := []*string{}
+	listServicesInput := &ecs.ListServicesInput{
+		Cluster: aws.String(clusterName),
+	}
+	err := ecsSvc.ListServicesPages(listServicesInput, func(page *ecs.ListServicesOutput, lastPage bool) bool {
+		serviceArns = append(serviceArns, page.ServiceArns...)
+		return true
+	})
+	if err != nil {
+		return nil, errors.New("Unable to list services: " + err.Error())
+	}
+
+	services := []*ecs.Service{}
+	for start := 0; start < len(serviceArns); start += describeServicesBatchSize {
+		end := start + describeServicesBatchSize
+		if end > len(serviceArns) {
+			end = len(serviceArns)
+		}
+
+		describeServicesInput := &ecs.DescribeServicesInput{
+			Services: serviceArns[start:end],
+			Cluster:  aws.String(clusterName),
+		}
+		describeServicesOutput, err := ecsSvc.DescribeServices(describeServicesInput)
+		if err != nil {
+			return nil, errors.New("Unable to describe services: " + err.Error())
+		}
+		services = append(services, describeServicesOutput.Services...)
+	}
+
+	return services, nil
+}
+
+// describeContainerInstances lists every container instance of the cluster
+// and describes them in batches, Fargate only clusters have none.
+func describeContainerInstances(ecsSvc *ecs.ECS, clusterName string) ([]*ecs.ContainerInstance, error) {
+	containerInstanceArns := []*string{}
+	listInstancesInput := &ecs.ListContainerInstancesInput{
+		Cluster: aws.String(clusterName),
+	}
+	err := ecsSvc.ListContainerInstancesPages(listInstancesInput, func(page *ecs.ListContainerInstancesOutput, lastPage bool) bool {
+		containerInstanceArns = append(containerInstanceArns, page.ContainerInstanceArns...)
+		return true
+	})
+	if err != nil {
+		return nil, errors.New("Unable to list container instances: " + err.Error())
+	}
+
+	containerInstances := []*ecs.ContainerInstance{}
+	for start := 0; start < len(containerInstanceArns); start += describeContainerInstancesBatchSize {
+		end := start + describeContainerInstancesBatchSize
+		if end > len(containerInstanceArns) {
+			end = len(containerInstanceArns)
+		}
+
+		describeInstancesInput := &ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(clusterName),
+			ContainerInstances: containerInstanceArns[start:end],
+		}
+		describeInstancesOutput, err := ecsSvc.DescribeContainerInstances(describeInstancesInput)
+		if err != nil {
+			return nil, errors.New("Unable to describe container instances: " + err.Error())
+		}
+		containerInstances = append(containerInstances, describeInstancesOutput.ContainerInstances...)
+	}
+
+	return containerInstances, nil
+}
+
+// describeEC2Instances describes the given instances in batches and returns
+// them keyed by instance id. Ids are passed as an instance-id filter because
+// InstanceIds fails the whole call with InvalidInstanceID.NotFound once a single
+// instance is gone, a filter just leaves the unknown ids out of the result.
+func describeEC2Instances(ec2Svc *ec2.EC2, instanceIds []*string) (map[string]*ec2.Instance, error) {
+	instances := make(map[string]*ec2.Instance)
+	for start := 0; start < len(instanceIds); start += describeInstancesBatchSize {
+		end := start + describeInstancesBatchSize
+		if end > len(instanceIds) {
+			end = len(instanceIds)
+		}
+
+		describeInstancesInput := &ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{
+				{
+					Name:   aws.String("instance-id"),
+					Values: instanceIds[start:end],
+				},
+			},
+		}
+		err := ec2Svc.DescribeInstancesPages(describeInstancesInput, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range page.Reservations {
+				for _, instance := range reservation.Instances {
+					instances[aws.StringValue(instance.InstanceId)] = instance
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, errors.New("Unable to describe instances: " + err.Error())
+		}
+	}
+
+	return instances, nil
+}
+
+func newTask(task *ecs.Task) *Task {
+	clusterTask := &Task{}
+	clusterTask.TaskArn = *task.TaskArn
+	clusterTask.TaskDefinitionArn = *task.TaskDefinitionArn
+	clusterTask.ContainerInstanceArn = aws.StringValue(task.ContainerInstanceArn)
+	clusterTask.LaunchType = aws.StringValue(task.LaunchType)
+	clusterTask.PlatformVersion = aws.StringValue(task.PlatformVersion)
+	clusterTask.Cpu = aws.StringValue(task.Cpu)
+	clusterTask.Memory = aws.StringValue(task.Memory)
+	clusterTask.LastStatus = aws.StringValue(task.LastStatus)
+	clusterTask.DesiredStatus = aws.StringValue(task.DesiredStatus)
+	clusterTask.HealthStatus = aws.StringValue(task.HealthStatus)
+	clusterTask.StartedBy = aws.StringValue(task.StartedBy)
+	clusterTask.Group = aws.StringValue(task.Group)
+	clusterTask.StoppedReason = aws.StringValue(task.StoppedReason)
+	clusterTask.CreatedAt = aws.TimeValue(task.CreatedAt)
+	clusterTask.StartedAt = aws.TimeValue(task.StartedAt)
+	clusterTask.StoppedAt = aws.TimeValue(task.StoppedAt)
+	if overrides := task.Overrides; overrides != nil {
+		clusterTask.TaskRoleArn = aws.StringValue(overrides.TaskRoleArn)
+		clusterTask.ExecutionRoleArn = aws.StringValue(overrides.ExecutionRoleArn)
+	}
+
+	networkInterfaces := []TaskNetworkInterface{}
+	for _, attachment := range task.Attachments {
+		if aws.StringValue(attachment.Type) != "ElasticNetworkInterface" {
+			continue
+		}
+		networkInterface := TaskNetworkInterface{}
+		for _, detail := range attachment.Details {
+			switch aws.StringValue(detail.Name) {
+			case "networkInterfaceId":
+				networkInterface.NetworkInterfaceId = aws.StringValue(detail.Value)
+			case "privateIPv4Address":
+				networkInterface.PrivateIPv4Address = aws.StringValue(detail.Value)
+			case "subnetId":
+				networkInterface.SubnetId = aws.StringValue(detail.Value)
+			case "macAddress":
+				networkInterface.MacAddress = aws.StringValue(detail.Value)
+			}
+		}
+		networkInterfaces = append(networkInterfaces, networkInterface)
+	}
+	clusterTask.NetworkInterfaces = networkInterfaces
+
+	taskContainers := []Container{}
+	for _, container := range task.Containers {
+		taskContainer := &Container{}
+		taskContainer.ContainerArn = *container.ContainerArn
+		taskContainer.Name = *container.Name
+		taskContainer.LastStatus = aws.StringValue(container.LastStatus)
+		taskContainer.HealthStatus = aws.StringValue(container.HealthStatus)
+		taskContainer.ExitCode = container.ExitCode
+		taskContainer.Reason = aws.StringValue(container.Reason)
+		taskContainer.ImageDigest = aws.StringValue(container.ImageDigest)
+
+		networkBindings := []NetworkBinding{}
+		for _, binding := range container.NetworkBindings {
+			networkBindings = append(networkBindings, NetworkBinding{
+				BindIP:        aws.StringValue(binding.BindIP),
+				ContainerPort: aws.Int64Value(binding.ContainerPort),
+				HostPort:      aws.Int64Value(binding.HostPort),
+				Protocol:      aws.StringValue(binding.Protocol),
+			})
+		}
+		taskContainer.NetworkBindings = networkBindings
+		taskContainers = append(taskContainers, *taskContainer)
+	}
+	clusterTask.Containers = taskContainers
+
+	return clusterTask
+}
+
+func newService(service *ecs.Service) *Service {
+	clusterService := &Service{}
+	clusterService.ServiceArn = *service.ServiceArn
+	clusterService.ServiceName = *service.ServiceName
+	clusterService.TaskDefinition = *service.TaskDefinition
+	clusterService.Status = aws.StringValue(service.Status)
+	clusterService.LaunchType = aws.StringValue(service.LaunchType)
+	clusterService.DesiredCount = aws.Int64Value(service.DesiredCount)
+	clusterService.RunningCount = aws.Int64Value(service.RunningCount)
+	clusterService.PendingCount = aws.Int64Value(service.PendingCount)
+
+	if config := service.DeploymentConfiguration; config != nil {
+		clusterService.DeploymentConfiguration.MaximumPercent = aws.Int64Value(config.MaximumPercent)
+		clusterService.DeploymentConfiguration.MinimumHealthyPercent = aws.Int64Value(config.MinimumHealthyPercent)
+	}
+
+	serviceDeployments := []ServiceDeployment{}
+	for _, deployment := range service.Deployments {
+		serviceDeployments = append(serviceDeployments, ServiceDeployment{
+			Id:              aws.StringValue(deployment.Id),
+			Status:          aws.StringValue(deployment.Status),
+			TaskDefinition:  aws.StringValue(deployment.TaskDefinition),
+			LaunchType:      aws.StringValue(deployment.LaunchType),
+			PlatformVersion: aws.StringValue(deployment.PlatformVersion),
+			DesiredCount:    aws.Int64Value(deployment.DesiredCount),
+			RunningCount:    aws.Int64Value(deployment.RunningCount),
+			PendingCount:    aws.Int64Value(deployment.PendingCount),
+			CreatedAt:       aws.TimeValue(deployment.CreatedAt),
+			UpdatedAt:       aws.TimeValue(deployment.UpdatedAt),
+		})
+	}
+	clusterService.Deployments = serviceDeployments
+
+	serviceLoadBalancers := []ServiceLoadBalancer{}
+	for _, loadBalancer := range service.LoadBalancers {
+		serviceLoadBalancers = append(serviceLoadBalancers, ServiceLoadBalancer{
+			LoadBalancerName: aws.StringValue(loadBalancer.LoadBalancerName),
+			TargetGroupArn:   aws.StringValue(loadBalancer.TargetGroupArn),
+			ContainerName:    aws.StringValue(loadBalancer.ContainerName),
+			ContainerPort:    aws.Int64Value(loadBalancer.ContainerPort),
+		})
+	}
+	clusterService.LoadBalancers = serviceLoadBalancers
+
+	placementConstraints := []PlacementConstraint{}
+	for _, constraint := range service.PlacementConstraints {
+		placementConstraints = append(placementConstraints, PlacementConstraint{
+			Type:       aws.StringValue(constraint.Type),
+			Expression: aws.StringValue(constraint.Expression),
+		})
+	}
+	clusterService.PlacementConstraints = placementConstraints
+
+	placementStrategy := []PlacementStrategy{}
+	for _, strategy := range service.PlacementStrategy {
+		placementStrategy = append(placementStrategy, PlacementStrategy{
+			Type:  aws.StringValue(strategy.Type),
+			Field: aws.StringValue(strategy.Field),
+		})
+	}
+	clusterService.PlacementStrategy = placementStrategy
+
+	// ECS returns service events newest first
+	serviceEvents := []ServiceEvent{}
+	for i, event := range service.Events {
+		if i >= maxServiceEvents {
+			break
+		}
+		serviceEvents = append(serviceEvents, ServiceEvent{
+			Id:        aws.StringValue(event.Id),
+			Message:   aws.StringValue(event.Message),
+			CreatedAt: aws.TimeValue(event.CreatedAt),
+		})
+	}
+	clusterService.Events = serviceEvents
+
+	return clusterService
+}
+
+func setContainerInstanceInfo(nodeInfo *NodeInfo, containerInstance *ecs.ContainerInstance) {
+	nodeInfo.Status = aws.StringValue(containerInstance.Status)
+	nodeInfo.AgentConnected = aws.BoolValue(containerInstance.AgentConnected)
+	nodeInfo.RunningTasksCount = aws.Int64Value(containerInstance.RunningTasksCount)
+	nodeInfo.PendingTasksCount = aws.Int64Value(containerInstance.PendingTasksCount)
+	if versionInfo := containerInstance.VersionInfo; versionInfo != nil {
+		nodeInfo.AgentVersion = aws.StringValue(versionInfo.AgentVersion)
+		nodeInfo.DockerVersion = aws.StringValue(versionInfo.DockerVersion)
+	}
+	nodeInfo.RegisteredResources = newNodeResources(containerInstance.RegisteredResources)
+	nodeInfo.RemainingResources = newNodeResources(containerInstance.RemainingResources)
+
+	// Built-in attributes (ecs.*) and agent capabilities (com.amazonaws.ecs.*)
+	// are present on every instance, only keep the ones users defined.
+	nodeAttributes := []NodeAttribute{}
+	for _, attribute := range containerInstance.Attributes {
+		name := aws.StringValue(attribute.Name)
+		if strings.HasPrefix(name, "ecs.") || strings.HasPrefix(name, "com.amazonaws.ecs.") {
+			continue
+		}
+		nodeAttributes = append(nodeAttributes, NodeAttribute{
+			Name:  name,
+			Value: aws.StringValue(attribute.Value),
+		})
+	}
+	nodeInfo.Attributes = nodeAttributes
+}
+
+func newNodeResources(resources []*ecs.Resource) NodeResources {
+	nodeResources := NodeResources{
+		Ports:    []string{},
+		UDPPorts: []string{},
+	}
+	for _, resource := range resources {
+		switch aws.StringValue(resource.Name) {
+		case "CPU":
+			nodeResources.CPU = aws.Int64Value(resource.IntegerValue)
+		case "MEMORY":
+			nodeResources.Memory = aws.Int64Value(resource.IntegerValue)
+		case "PORTS":
+			nodeResources.Ports = aws.StringValueSlice(resource.StringSetValue)
+		case "PORTS_UDP":
+			nodeResources.UDPPorts = aws.StringValueSlice(resource.StringSetValue)
+		}
+	}
+
+	return nodeResources
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,187 @@
+package awsecs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/hyperpilotio/ingestor/capturer/awscommon"
+)
+
+const (
+	testClusterArn = "arn:aws:ecs:us-east-1:123456789012:cluster/web"
+	testTaskArn    = "arn:aws:ecs:us-east-1:123456789012:task/web/0123456789abcdef"
+	testServiceArn = "arn:aws:ecs:us-east-1:123456789012:service/web/frontend"
+	testTaskDefArn = "arn:aws:ecs:us-east-1:123456789012:task-definition/frontend:3"
+)
+
+// ecsResponses are the replies of the stand-in ECS endpoint, keyed by operation
+var ecsResponses = map[string]interface{}{
+	"ListClusters": map[string]interface{}{
+		"clusterArns": []string{testClusterArn},
+	},
+	"DescribeClusters": map[string]interface{}{
+		"clusters": []interface{}{
+			map[string]interface{}{
+				"clusterArn":        testClusterArn,
+				"clusterName":       "web",
+				"status":            "ACTIVE",
+				"runningTasksCount": 1,
+				"settings": []interface{}{
+					map[string]interface{}{"name": "containerInsights", "value": "enabled"},
+				},
+				"tags": []interface{}{
+					map[string]interface{}{"key": "team", "value": "frontend"},
+				},
+			},
+		},
+	},
+	"ListTasks": map[string]interface{}{
+		"taskArns": []string{testTaskArn},
+	},
+	"DescribeTasks": map[string]interface{}{
+		"tasks": []interface{}{
+			map[string]interface{}{
+				"taskArn":           testTaskArn,
+				"taskDefinitionArn": testTaskDefArn,
+				"launchType":        "FARGATE",
+				"lastStatus":        "RUNNING",
+				"containers": []interface{}{
+					map[string]interface{}{
+						"containerArn": testTaskArn + "/nginx",
+						"name":         "nginx",
+						"lastStatus":   "RUNNING",
+					},
+				},
+			},
+		},
+	},
+	"ListContainerInstances": map[string]interface{}{
+		"containerInstanceArns": []string{},
+	},
+	"ListServices": map[string]interface{}{
+		"serviceArns": []string{testServiceArn},
+	},
+	"DescribeServices": map[string]interface{}{
+		"services": []interface{}{
+			map[string]interface{}{
+				"serviceArn":     testServiceArn,
+				"serviceName":    "frontend",
+				"taskDefinition": testTaskDefArn,
+				"status":         "ACTIVE",
+				"launchType":     "FARGATE",
+				"desiredCount":   1,
+				"runningCount":   1,
+			},
+		},
+	},
+	"DescribeTaskDefinition": map[string]interface{}{
+		"taskDefinition": map[string]interface{}{
+			"taskDefinitionArn": testTaskDefArn,
+			"family":            "frontend",
+		},
+	},
+}
+
+// newTestServer serves the ECS JSON protocol from ecsResponses and fails the
+// test on any request meant for another service.
+func newTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.Header.Get("X-Amz-Target")
+		operation := target[strings.LastIndex(target, ".")+1:]
+		response, ok := ecsResponses[operation]
+		if !strings.HasPrefix(target, "AmazonEC2ContainerServiceV20141113.") || !ok {
+			t.Errorf("Unexpected request %s %s with target %q", r.Method, r.URL.Path, target)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Errorf("Unable to encode %s response: %s", operation, err.Error())
+		}
+	}))
+}
+
+func TestGetClustersAgainstEndpointOverride(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	os.Setenv("awsId", "AKIDTEST")
+	os.Setenv("awsSecret", "secret")
+
+	// every service points at the stand-in so nothing reaches AWS
+	config := viper.New()
+	config.Set("endpoints", map[string]string{
+		"autoscaling": server.URL,
+		"ecs":         server.URL,
+		"ec2":         server.URL,
+		"elbv2":       server.URL,
+		"iam":         server.URL,
+	})
+	config.Set("throttling.maxRetries", 0)
+
+	sess, err := awscommon.NewSession(config, "us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to create session: %s", err.Error())
+	}
+	filter, err := NewClusterFilter(config, "us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to create cluster filter: %s", err.Error())
+	}
+
+	capturer := AWSECSCapturer{
+		Region:    "us-east-1",
+		Sess:      sess,
+		Filter:    filter,
+		Throttles: awscommon.NewThrottleCounter(sess),
+	}
+	deployments, err := capturer.GetClusters()
+	if err != nil {
+		t.Fatalf("Unable to get clusters: %s", err.Error())
+	}
+
+	if len(deployments.Clusters) != 1 {
+		t.Fatalf("Expected 1 cluster, got %d", len(deployments.Clusters))
+	}
+	cluster := deployments.Clusters[0]
+	if cluster.ClusterName != "web" || cluster.ContainerInsights != "enabled" {
+		t.Errorf("Unexpected cluster %s with container insights %q", cluster.ClusterName, cluster.ContainerInsights)
+	}
+	if len(cluster.Tags) != 1 || cluster.Tags[0].Key != "team" {
+		t.Errorf("Unexpected cluster tags %v", cluster.Tags)
+	}
+	if len(cluster.NodeInfos) != 0 {
+		t.Errorf("Expected no container instances, got %d", len(cluster.NodeInfos))
+	}
+	if len(cluster.FargateTasks) != 1 || cluster.FargateTasks[0].TaskArn != testTaskArn {
+		t.Errorf("Expected Fargate task %s, got %v", testTaskArn, cluster.FargateTasks)
+	}
+	if len(cluster.Services) != 1 || cluster.Services[0].ServiceName != "frontend" {
+		t.Errorf("Expected service frontend, got %v", cluster.Services)
+	}
+}
+
+func TestTrustedEntities(t *testing.T) {
+	policies := map[string]string{
+		"list":   `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ecs-tasks.amazonaws.com"},"Action":"sts:AssumeRole"}]}`,
+		"object": `{"Version":"2012-10-17","Statement":{"Effect":"Allow","Principal":{"Service":["ecs-tasks.amazonaws.com"]},"Action":"sts:AssumeRole"}}`,
+	}
+	for name, policy := range policies {
+		entities, err := trustedEntities(policy)
+		if err != nil {
+			t.Errorf("Unable to parse %s policy: %s", name, err.Error())
+		} else if len(entities) != 1 || entities[0] != "Service:ecs-tasks.amazonaws.com" {
+			t.Errorf("Unexpected entities of %s policy: %v", name, entities)
+		}
+	}
+
+	if _, err := trustedEntities(`{"Statement":"Allow"}`); err == nil {
+		t.Errorf("Expected an error for a malformed statement")
+	}
+}
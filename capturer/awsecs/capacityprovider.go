@@ -0,0 +1,117 @@
+package awsecs
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// describeCapacityProvidersBatchSize is the maximum number of capacity providers DescribeCapacityProviders accepts
+const describeCapacityProvidersBatchSize = 100
+
+type ManagedScaling struct {
+	Status                 string `json:"Status" bson:"Status"`
+	TargetCapacity         int64  `json:"TargetCapacity" bson:"TargetCapacity"`
+	MinimumScalingStepSize int64  `json:"MinimumScalingStepSize" bson:"MinimumScalingStepSize"`
+	MaximumScalingStepSize int64  `json:"MaximumScalingStepSize" bson:"MaximumScalingStepSize"`
+}
+
+type CapacityProvider struct {
+	Name                         string          `json:"Name" bson:"Name"`
+	CapacityProviderArn          string          `json:"CapacityProviderArn" bson:"CapacityProviderArn"`
+	Status                       string          `json:"Status" bson:"Status"`
+	AutoScalingGroupArn          string          `json:"AutoScalingGroupArn" bson:"AutoScalingGroupArn"`
+	ManagedScaling               *ManagedScaling `json:"ManagedScaling" bson:"ManagedScaling"`
+	ManagedTerminationProtection string          `json:"ManagedTerminationProtection" bson:"ManagedTerminationProtection"`
+}
+
+type CapacityProviderStrategyItem struct {
+	CapacityProvider string `json:"CapacityProvider" bson:"CapacityProvider"`
+	Weight           int64  `json:"Weight" bson:"Weight"`
+	Base             int64  `json:"Base" bson:"Base"`
+}
+
+// capacityProviderCache describes capacity providers once per capture, as
+// they are commonly shared between the clusters of a region.
+type capacityProviderCache struct {
+	ecsSvc    *ecs.ECS
+	providers map[string]*CapacityProvider
+}
+
+func newCapacityProviderCache(ecsSvc *ecs.ECS) *capacityProviderCache {
+	return &capacityProviderCache{
+		ecsSvc:    ecsSvc,
+		providers: make(map[string]*CapacityProvider),
+	}
+}
+
+// describe returns the named capacity providers, describing the ones not seen yet
+func (cache *capacityProviderCache) describe(names []string) ([]CapacityProvider, error) {
+	missing := []string{}
+	for _, name := range names {
+		if _, ok := cache.providers[name]; !ok && !containsString(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+
+	for start := 0; start < len(missing); start += describeCapacityProvidersBatchSize {
+		end := start + describeCapacityProvidersBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+
+		// results are paged even when the providers are named
+		describeCapacityProvidersInput := &ecs.DescribeCapacityProvidersInput{
+			CapacityProviders: aws.StringSlice(missing[start:end]),
+		}
+		for {
+			describeCapacityProvidersOutput, err := cache.ecsSvc.DescribeCapacityProviders(describeCapacityProvidersInput)
+			if err != nil {
+				return nil, errors.New("Unable to describe capacity providers: " + err.Error())
+			}
+
+			for _, provider := range describeCapacityProvidersOutput.CapacityProviders {
+				capacityProvider := newCapacityProvider(provider)
+				cache.providers[capacityProvider.Name] = capacityProvider
+			}
+
+			if aws.StringValue(describeCapacityProvidersOutput.NextToken) == "" {
+				break
+			}
+			describeCapacityProvidersInput.NextToken = describeCapacityProvidersOutput.NextToken
+		}
+	}
+
+	capacityProviders := []CapacityProvider{}
+	for _, name := range names {
+		if capacityProvider, ok := cache.providers[name]; ok {
+			capacityProviders = append(capacityProviders, *capacityProvider)
+		}
+	}
+
+	return capacityProviders, nil
+}
+
+func newCapacityProvider(provider *ecs.CapacityProvider) *CapacityProvider {
+	capacityProvider := &CapacityProvider{}
+	capacityProvider.Name = aws.StringValue(provider.Name)
+	capacityProvider.CapacityProviderArn = aws.StringValue(provider.CapacityProviderArn)
+	capacityProvider.Status = aws.StringValue(provider.Status)
+
+	// FARGATE and FARGATE_SPOT have no auto scaling group
+	if groupProvider := provider.AutoScalingGroupProvider; groupProvider != nil {
+		capacityProvider.AutoScalingGroupArn = aws.StringValue(groupProvider.AutoScalingGroupArn)
+		capacityProvider.ManagedTerminationProtection = aws.StringValue(groupProvider.ManagedTerminationProtection)
+		if scaling := groupProvider.ManagedScaling; scaling != nil {
+			capacityProvider.ManagedScaling = &ManagedScaling{
+				Status:                 aws.StringValue(scaling.Status),
+				TargetCapacity:         aws.Int64Value(scaling.TargetCapacity),
+				MinimumScalingStepSize: aws.Int64Value(scaling.MinimumScalingStepSize),
+				MaximumScalingStepSize: aws.Int64Value(scaling.MaximumScalingStepSize),
+			}
+		}
+	}
+
+	return capacityProvider
+}
@@ -0,0 +1,187 @@
+package awsecs
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// ClusterFilter decides which clusters of a region are captured. Include and
+// Exclude hold cluster names or glob patterns, tag filters hold key=value
+// pairs where the value may also be a glob pattern.
+type ClusterFilter struct {
+	Include     []string
+	Exclude     []string
+	IncludeTags map[string]string
+	ExcludeTags map[string]string
+}
+
+// NewClusterFilter reads the filter of the region from aws.clusterFilters,
+// falling back to aws.clusterFilters.default when the region has none.
+func NewClusterFilter(config *viper.Viper, region string) (*ClusterFilter, error) {
+	filter := &ClusterFilter{
+		IncludeTags: make(map[string]string),
+		ExcludeTags: make(map[string]string),
+	}
+
+	filtersConfig := config.Sub("clusterFilters")
+	if filtersConfig == nil {
+		return filter, nil
+	}
+
+	filterConfig := filtersConfig.Sub(region)
+	if filterConfig == nil {
+		filterConfig = filtersConfig.Sub("default")
+	}
+	if filterConfig == nil {
+		return filter, nil
+	}
+
+	filter.Include = filterConfig.GetStringSlice("include")
+	filter.Exclude = filterConfig.GetStringSlice("exclude")
+	for _, pattern := range append(filter.Include, filter.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.New("Invalid cluster name pattern " + pattern + ": " + err.Error())
+		}
+	}
+
+	// tags are configured as key=value strings since viper lowercases map keys
+	// and tag keys are case sensitive
+	var err error
+	if filter.IncludeTags, err = parseTagFilters(filterConfig.GetStringSlice("includeTags")); err != nil {
+		return nil, err
+	}
+	if filter.ExcludeTags, err = parseTagFilters(filterConfig.GetStringSlice("excludeTags")); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// DescribeClusters finds the clusters of the region and describes the ones
+// matching the filter, with their settings and tags.
+func DescribeClusters(ecsSvc *ecs.ECS, filter *ClusterFilter, region string) ([]*ecs.Cluster, error) {
+	// find clusters on region
+	clusterArns := []*string{}
+	listClustersErr := ecsSvc.ListClustersPages(&ecs.ListClustersInput{}, func(page *ecs.ListClustersOutput, lastPage bool) bool {
+		clusterArns = append(clusterArns, page.ClusterArns...)
+		return true
+	})
+	if listClustersErr != nil {
+		return nil, errors.New("Unable to find any clusters: " + listClustersErr.Error())
+	}
+
+	// regions running only EC2 instances or Lambda functions have no clusters,
+	// which is not an error
+	if len(clusterArns) == 0 {
+		glog.V(1).Infof("No clusters found in region %s", region)
+		return []*ecs.Cluster{}, nil
+	}
+
+	// names are part of the ARN, so name filters are applied before describing
+	matchedArns := []*string{}
+	for _, clusterArn := range clusterArns {
+		arn := aws.StringValue(clusterArn)
+		clusterName := arn[strings.LastIndex(arn, "/")+1:]
+		if filter.MatchName(clusterName) {
+			matchedArns = append(matchedArns, clusterArn)
+		} else {
+			glog.V(2).Infof("Skipping filtered cluster %s in region %s", clusterName, region)
+		}
+	}
+
+	// use clusterArns get region's clusters information
+	clusters := []*ecs.Cluster{}
+	for start := 0; start < len(matchedArns); start += describeClustersBatchSize {
+		end := start + describeClustersBatchSize
+		if end > len(matchedArns) {
+			end = len(matchedArns)
+		}
+
+		describeClustersInput := &ecs.DescribeClustersInput{
+			Clusters: matchedArns[start:end],
+			Include: []*string{
+				aws.String(ecs.ClusterFieldSettings),
+				aws.String(ecs.ClusterFieldTags),
+			},
+		}
+		describeClustersOutput, err := ecsSvc.DescribeClusters(describeClustersInput)
+		if err != nil {
+			return nil, errors.New("Unable to describe clusters: " + err.Error())
+		}
+
+		for _, cluster := range describeClustersOutput.Clusters {
+			if filter.MatchTags(cluster.Tags) {
+				clusters = append(clusters, cluster)
+			} else {
+				glog.V(2).Infof("Skipping cluster %s in region %s by tags", aws.StringValue(cluster.ClusterName), region)
+			}
+		}
+	}
+
+	return clusters, nil
+}
+
+func parseTagFilters(tagFilters []string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, tagFilter := range tagFilters {
+		parts := strings.SplitN(tagFilter, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.New("Invalid tag filter, expected key=value: " + tagFilter)
+		}
+		if _, err := path.Match(parts[1], ""); err != nil {
+			return nil, errors.New("Invalid tag filter pattern " + tagFilter + ": " + err.Error())
+		}
+		tags[parts[0]] = parts[1]
+	}
+
+	return tags, nil
+}
+
+// MatchName returns whether a cluster is captured based on its name, exclusions
+// take precedence and an empty include list matches every cluster.
+func (filter *ClusterFilter) MatchName(clusterName string) bool {
+	if matchAny(filter.Exclude, clusterName) {
+		return false
+	}
+
+	return len(filter.Include) == 0 || matchAny(filter.Include, clusterName)
+}
+
+// MatchTags returns whether a cluster carries every included tag and none of the excluded ones
+func (filter *ClusterFilter) MatchTags(tags []*ecs.Tag) bool {
+	clusterTags := make(map[string]string)
+	for _, tag := range tags {
+		clusterTags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	for key, pattern := range filter.ExcludeTags {
+		if value, ok := clusterTags[key]; ok && matchAny([]string{pattern}, value) {
+			return false
+		}
+	}
+
+	for key, pattern := range filter.IncludeTags {
+		if value, ok := clusterTags[key]; !ok || !matchAny([]string{pattern}, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+
+	return false
+}
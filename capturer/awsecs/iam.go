@@ -0,0 +1,232 @@
+package awsecs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+type IAMRole struct {
+	RoleName        string   `json:"RoleName" bson:"RoleName"`
+	Arn             string   `json:"Arn" bson:"Arn"`
+	ManagedPolicies []string `json:"ManagedPolicies" bson:"ManagedPolicies"`
+	InlinePolicies  []string `json:"InlinePolicies" bson:"InlinePolicies"`
+	TrustedEntities []string `json:"TrustedEntities" bson:"TrustedEntities"`
+	TrustPolicy     string   `json:"TrustPolicy" bson:"TrustPolicy"`
+}
+
+// roleResolver resolves the roles of task definitions, caching task
+// definitions and roles for the duration of a capture.
+type roleResolver struct {
+	ecsSvc          *ecs.ECS
+	iamSvc          *iam.IAM
+	taskDefinitions map[string]*ecs.TaskDefinition
+	roles           map[string]*IAMRole
+}
+
+func newRoleResolver(ecsSvc *ecs.ECS, iamSvc *iam.IAM) *roleResolver {
+	return &roleResolver{
+		ecsSvc:          ecsSvc,
+		iamSvc:          iamSvc,
+		taskDefinitions: make(map[string]*ecs.TaskDefinition),
+		roles:           make(map[string]*IAMRole),
+	}
+}
+
+// resolveCluster sets the task and execution roles of the cluster's services
+// and tasks, and stores the roles they use on the cluster.
+func (resolver *roleResolver) resolveCluster(cluster *Cluster) error {
+	roleArns := []string{}
+	addRoles := func(arns ...string) {
+		for _, arn := range arns {
+			if arn != "" && !containsString(roleArns, arn) {
+				roleArns = append(roleArns, arn)
+			}
+		}
+	}
+
+	for i := range cluster.Services {
+		service := &cluster.Services[i]
+		taskDefinition, err := resolver.taskDefinition(service.TaskDefinition)
+		if err != nil {
+			return err
+		}
+		service.TaskRoleArn = aws.StringValue(taskDefinition.TaskRoleArn)
+		service.ExecutionRoleArn = aws.StringValue(taskDefinition.ExecutionRoleArn)
+		addRoles(service.TaskRoleArn, service.ExecutionRoleArn)
+	}
+
+	tasks := []*Task{}
+	for i := range cluster.NodeInfos {
+		for j := range cluster.NodeInfos[i].Tasks {
+			tasks = append(tasks, &cluster.NodeInfos[i].Tasks[j])
+		}
+	}
+	for i := range cluster.FargateTasks {
+		tasks = append(tasks, &cluster.FargateTasks[i])
+	}
+
+	for _, task := range tasks {
+		taskDefinition, err := resolver.taskDefinition(task.TaskDefinitionArn)
+		if err != nil {
+			return err
+		}
+		// roles overridden when the task was run are already set
+		if task.TaskRoleArn == "" {
+			task.TaskRoleArn = aws.StringValue(taskDefinition.TaskRoleArn)
+		}
+		if task.ExecutionRoleArn == "" {
+			task.ExecutionRoleArn = aws.StringValue(taskDefinition.ExecutionRoleArn)
+		}
+		addRoles(task.TaskRoleArn, task.ExecutionRoleArn)
+	}
+
+	cluster.Roles = []IAMRole{}
+	for _, roleArn := range roleArns {
+		cluster.Roles = append(cluster.Roles, *resolver.role(roleArn))
+	}
+
+	return nil
+}
+
+func (resolver *roleResolver) taskDefinition(taskDefinitionArn string) (*ecs.TaskDefinition, error) {
+	if taskDefinition, ok := resolver.taskDefinitions[taskDefinitionArn]; ok {
+		return taskDefinition, nil
+	}
+
+	describeTaskDefinitionInput := &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefinitionArn),
+	}
+	describeTaskDefinitionOutput, err := resolver.ecsSvc.DescribeTaskDefinition(describeTaskDefinitionInput)
+	if err != nil {
+		return nil, errors.New("Unable to describe task definition " + taskDefinitionArn + ": " + err.Error())
+	}
+
+	resolver.taskDefinitions[taskDefinitionArn] = describeTaskDefinitionOutput.TaskDefinition
+	return describeTaskDefinitionOutput.TaskDefinition, nil
+}
+
+// role describes the role and its policies. The ingestor often runs without
+// IAM read permissions, so failures are logged and only the ARN is kept
+// rather than failing the whole capture.
+func (resolver *roleResolver) role(roleArn string) *IAMRole {
+	if role, ok := resolver.roles[roleArn]; ok {
+		return role
+	}
+
+	role := &IAMRole{
+		Arn:             roleArn,
+		RoleName:        roleArn[strings.LastIndex(roleArn, "/")+1:],
+		ManagedPolicies: []string{},
+		InlinePolicies:  []string{},
+		TrustedEntities: []string{},
+	}
+	resolver.roles[roleArn] = role
+
+	getRoleOutput, err := resolver.iamSvc.GetRole(&iam.GetRoleInput{
+		RoleName: aws.String(role.RoleName),
+	})
+	if err != nil {
+		glog.Warningf("Unable to get role %s: %s", roleArn, err.Error())
+		return role
+	}
+
+	if trustPolicy, err := url.QueryUnescape(aws.StringValue(getRoleOutput.Role.AssumeRolePolicyDocument)); err != nil {
+		glog.Warningf("Unable to decode trust policy of role %s: %s", roleArn, err.Error())
+	} else {
+		role.TrustPolicy = trustPolicy
+		if entities, err := trustedEntities(trustPolicy); err != nil {
+			glog.Warningf("Unable to parse trust policy of role %s: %s", roleArn, err.Error())
+		} else {
+			role.TrustedEntities = entities
+		}
+	}
+
+	listAttachedInput := &iam.ListAttachedRolePoliciesInput{
+		RoleName: aws.String(role.RoleName),
+	}
+	err = resolver.iamSvc.ListAttachedRolePoliciesPages(listAttachedInput, func(page *iam.ListAttachedRolePoliciesOutput, lastPage bool) bool {
+		for _, policy := range page.AttachedPolicies {
+			role.ManagedPolicies = append(role.ManagedPolicies, aws.StringValue(policy.PolicyName))
+		}
+		return true
+	})
+	if err != nil {
+		glog.Warningf("Unable to list attached policies of role %s: %s", roleArn, err.Error())
+	}
+
+	listInlineInput := &iam.ListRolePoliciesInput{
+		RoleName: aws.String(role.RoleName),
+	}
+	err = resolver.iamSvc.ListRolePoliciesPages(listInlineInput, func(page *iam.ListRolePoliciesOutput, lastPage bool) bool {
+		role.InlinePolicies = append(role.InlinePolicies, aws.StringValueSlice(page.PolicyNames)...)
+		return true
+	})
+	if err != nil {
+		glog.Warningf("Unable to list inline policies of role %s: %s", roleArn, err.Error())
+	}
+
+	return role
+}
+
+type policyStatement struct {
+	Effect    string
+	Principal interface{}
+}
+
+// trustedEntities returns the principals allowed to assume a role as
+// type:identifier pairs, e.g. Service:ecs-tasks.amazonaws.com
+func trustedEntities(trustPolicy string) ([]string, error) {
+	var document struct {
+		Statement json.RawMessage
+	}
+	if err := json.Unmarshal([]byte(trustPolicy), &document); err != nil {
+		return nil, err
+	}
+
+	// IAM accepts a single statement object as well as a list of them
+	statements := []policyStatement{}
+	if err := json.Unmarshal(document.Statement, &statements); err != nil {
+		var statement policyStatement
+		if err := json.Unmarshal(document.Statement, &statement); err != nil {
+			return nil, errors.New("Statement is neither an object nor a list: " + err.Error())
+		}
+		statements = append(statements, statement)
+	}
+
+	entities := []string{}
+	for _, statement := range statements {
+		if statement.Effect != "Allow" {
+			continue
+		}
+
+		switch principal := statement.Principal.(type) {
+		case string:
+			entities = append(entities, principal)
+		case map[string]interface{}:
+			for principalType, value := range principal {
+				switch identifiers := value.(type) {
+				case string:
+					entities = append(entities, principalType+":"+identifiers)
+				case []interface{}:
+					for _, identifier := range identifiers {
+						if id, ok := identifier.(string); ok {
+							entities = append(entities, principalType+":"+id)
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(entities)
+
+	return entities, nil
+}
@@ -0,0 +1,266 @@
+package awsecs
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+// elbv2BatchSize is the maximum number of ARNs the ELBv2 describe calls accept
+const elbv2BatchSize = 20
+
+type Listener struct {
+	ListenerArn            string   `json:"ListenerArn" bson:"ListenerArn"`
+	Protocol               string   `json:"Protocol" bson:"Protocol"`
+	Port                   int64    `json:"Port" bson:"Port"`
+	DefaultTargetGroupArns []string `json:"DefaultTargetGroupArns" bson:"DefaultTargetGroupArns"`
+}
+
+type LoadBalancer struct {
+	LoadBalancerArn  string     `json:"LoadBalancerArn" bson:"LoadBalancerArn"`
+	LoadBalancerName string     `json:"LoadBalancerName" bson:"LoadBalancerName"`
+	DNSName          string     `json:"DNSName" bson:"DNSName"`
+	Type             string     `json:"Type" bson:"Type"`
+	Scheme           string     `json:"Scheme" bson:"Scheme"`
+	State            string     `json:"State" bson:"State"`
+	Listeners        []Listener `json:"Listeners" bson:"Listeners"`
+}
+
+type TargetHealth struct {
+	TargetId    string `json:"TargetId" bson:"TargetId"`
+	Port        int64  `json:"Port" bson:"Port"`
+	State       string `json:"State" bson:"State"`
+	Reason      string `json:"Reason" bson:"Reason"`
+	Description string `json:"Description" bson:"Description"`
+}
+
+type TargetGroup struct {
+	TargetGroupArn   string         `json:"TargetGroupArn" bson:"TargetGroupArn"`
+	TargetGroupName  string         `json:"TargetGroupName" bson:"TargetGroupName"`
+	Protocol         string         `json:"Protocol" bson:"Protocol"`
+	Port             int64          `json:"Port" bson:"Port"`
+	TargetType       string         `json:"TargetType" bson:"TargetType"`
+	HealthCheckPath  string         `json:"HealthCheckPath" bson:"HealthCheckPath"`
+	LoadBalancerArns []string       `json:"LoadBalancerArns" bson:"LoadBalancerArns"`
+	Targets          []TargetHealth `json:"Targets" bson:"Targets"`
+}
+
+// TaskTargetHealth is the health of a task in one of its service's target groups
+type TaskTargetHealth struct {
+	TargetGroupArn string `json:"TargetGroupArn" bson:"TargetGroupArn"`
+	TargetId       string `json:"TargetId" bson:"TargetId"`
+	Port           int64  `json:"Port" bson:"Port"`
+	State          string `json:"State" bson:"State"`
+	Reason         string `json:"Reason" bson:"Reason"`
+}
+
+// describeLoadBalancers describes the target groups attached to the cluster's
+// services with their load balancers and listeners, and links target health
+// back to the services and tasks.
+func describeLoadBalancers(elbSvc *elbv2.ELBV2, cluster *Cluster) error {
+	targetGroupArns := []string{}
+	for _, service := range cluster.Services {
+		for _, loadBalancer := range service.LoadBalancers {
+			if arn := loadBalancer.TargetGroupArn; arn != "" && !containsString(targetGroupArns, arn) {
+				targetGroupArns = append(targetGroupArns, arn)
+			}
+		}
+	}
+
+	cluster.TargetGroups = []TargetGroup{}
+	cluster.LoadBalancers = []LoadBalancer{}
+	loadBalancerArns := []string{}
+	targetGroups := make(map[string]*TargetGroup)
+	for start := 0; start < len(targetGroupArns); start += elbv2BatchSize {
+		end := start + elbv2BatchSize
+		if end > len(targetGroupArns) {
+			end = len(targetGroupArns)
+		}
+
+		describeTargetGroupsInput := &elbv2.DescribeTargetGroupsInput{
+			TargetGroupArns: aws.StringSlice(targetGroupArns[start:end]),
+		}
+		describeTargetGroupsOutput, err := elbSvc.DescribeTargetGroups(describeTargetGroupsInput)
+		if err != nil {
+			return errors.New("Unable to describe target groups: " + err.Error())
+		}
+
+		for _, group := range describeTargetGroupsOutput.TargetGroups {
+			targetGroup, err := newTargetGroup(elbSvc, group)
+			if err != nil {
+				return err
+			}
+			for _, arn := range targetGroup.LoadBalancerArns {
+				if !containsString(loadBalancerArns, arn) {
+					loadBalancerArns = append(loadBalancerArns, arn)
+				}
+			}
+			targetGroups[targetGroup.TargetGroupArn] = targetGroup
+			cluster.TargetGroups = append(cluster.TargetGroups, *targetGroup)
+		}
+	}
+
+	for start := 0; start < len(loadBalancerArns); start += elbv2BatchSize {
+		end := start + elbv2BatchSize
+		if end > len(loadBalancerArns) {
+			end = len(loadBalancerArns)
+		}
+
+		describeLoadBalancersInput := &elbv2.DescribeLoadBalancersInput{
+			LoadBalancerArns: aws.StringSlice(loadBalancerArns[start:end]),
+		}
+		describeLoadBalancersOutput, err := elbSvc.DescribeLoadBalancers(describeLoadBalancersInput)
+		if err != nil {
+			return errors.New("Unable to describe load balancers: " + err.Error())
+		}
+
+		for _, loadBalancer := range describeLoadBalancersOutput.LoadBalancers {
+			clusterLoadBalancer, err := newLoadBalancer(elbSvc, loadBalancer)
+			if err != nil {
+				return err
+			}
+			cluster.LoadBalancers = append(cluster.LoadBalancers, *clusterLoadBalancer)
+		}
+	}
+
+	for i := range cluster.Services {
+		for j := range cluster.Services[i].LoadBalancers {
+			serviceLoadBalancer := &cluster.Services[i].LoadBalancers[j]
+			serviceLoadBalancer.LoadBalancerArns = []string{}
+			if targetGroup, ok := targetGroups[serviceLoadBalancer.TargetGroupArn]; ok {
+				serviceLoadBalancer.LoadBalancerArns = targetGroup.LoadBalancerArns
+			}
+		}
+	}
+
+	for i := range cluster.NodeInfos {
+		nodeInfo := &cluster.NodeInfos[i]
+		for j := range nodeInfo.Tasks {
+			linkTaskTargets(&nodeInfo.Tasks[j], nodeInfo.Instance.InstanceId, cluster.TargetGroups)
+		}
+	}
+	for i := range cluster.FargateTasks {
+		linkTaskTargets(&cluster.FargateTasks[i], "", cluster.TargetGroups)
+	}
+
+	return nil
+}
+
+func newTargetGroup(elbSvc *elbv2.ELBV2, group *elbv2.TargetGroup) (*TargetGroup, error) {
+	targetGroup := &TargetGroup{}
+	targetGroup.TargetGroupArn = aws.StringValue(group.TargetGroupArn)
+	targetGroup.TargetGroupName = aws.StringValue(group.TargetGroupName)
+	targetGroup.Protocol = aws.StringValue(group.Protocol)
+	targetGroup.Port = aws.Int64Value(group.Port)
+	targetGroup.TargetType = aws.StringValue(group.TargetType)
+	targetGroup.HealthCheckPath = aws.StringValue(group.HealthCheckPath)
+	targetGroup.LoadBalancerArns = aws.StringValueSlice(group.LoadBalancerArns)
+
+	describeTargetHealthInput := &elbv2.DescribeTargetHealthInput{
+		TargetGroupArn: group.TargetGroupArn,
+	}
+	describeTargetHealthOutput, err := elbSvc.DescribeTargetHealth(describeTargetHealthInput)
+	if err != nil {
+		return nil, errors.New("Unable to describe target health of " + targetGroup.TargetGroupArn + ": " + err.Error())
+	}
+
+	targets := []TargetHealth{}
+	for _, description := range describeTargetHealthOutput.TargetHealthDescriptions {
+		target := TargetHealth{}
+		if description.Target != nil {
+			target.TargetId = aws.StringValue(description.Target.Id)
+			target.Port = aws.Int64Value(description.Target.Port)
+		}
+		if description.TargetHealth != nil {
+			target.State = aws.StringValue(description.TargetHealth.State)
+			target.Reason = aws.StringValue(description.TargetHealth.Reason)
+			target.Description = aws.StringValue(description.TargetHealth.Description)
+		}
+		targets = append(targets, target)
+	}
+	targetGroup.Targets = targets
+
+	return targetGroup, nil
+}
+
+func newLoadBalancer(elbSvc *elbv2.ELBV2, loadBalancer *elbv2.LoadBalancer) (*LoadBalancer, error) {
+	clusterLoadBalancer := &LoadBalancer{}
+	clusterLoadBalancer.LoadBalancerArn = aws.StringValue(loadBalancer.LoadBalancerArn)
+	clusterLoadBalancer.LoadBalancerName = aws.StringValue(loadBalancer.LoadBalancerName)
+	clusterLoadBalancer.DNSName = aws.StringValue(loadBalancer.DNSName)
+	clusterLoadBalancer.Type = aws.StringValue(loadBalancer.Type)
+	clusterLoadBalancer.Scheme = aws.StringValue(loadBalancer.Scheme)
+	if loadBalancer.State != nil {
+		clusterLoadBalancer.State = aws.StringValue(loadBalancer.State.Code)
+	}
+
+	listeners := []Listener{}
+	describeListenersInput := &elbv2.DescribeListenersInput{
+		LoadBalancerArn: loadBalancer.LoadBalancerArn,
+	}
+	err := elbSvc.DescribeListenersPages(describeListenersInput, func(page *elbv2.DescribeListenersOutput, lastPage bool) bool {
+		for _, listener := range page.Listeners {
+			defaultTargetGroupArns := []string{}
+			for _, action := range listener.DefaultActions {
+				if arn := aws.StringValue(action.TargetGroupArn); arn != "" {
+					defaultTargetGroupArns = append(defaultTargetGroupArns, arn)
+				}
+			}
+			listeners = append(listeners, Listener{
+				ListenerArn:            aws.StringValue(listener.ListenerArn),
+				Protocol:               aws.StringValue(listener.Protocol),
+				Port:                   aws.Int64Value(listener.Port),
+				DefaultTargetGroupArns: defaultTargetGroupArns,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.New("Unable to describe listeners of " + clusterLoadBalancer.LoadBalancerArn + ": " + err.Error())
+	}
+	clusterLoadBalancer.Listeners = listeners
+
+	return clusterLoadBalancer, nil
+}
+
+// linkTaskTargets finds the task among the registered targets, by ENI address
+// for ip target groups and by instance id and host port for instance ones.
+func linkTaskTargets(task *Task, instanceId string, targetGroups []TargetGroup) {
+	addresses := []string{}
+	for _, networkInterface := range task.NetworkInterfaces {
+		addresses = append(addresses, networkInterface.PrivateIPv4Address)
+	}
+
+	hostPorts := []string{}
+	for _, container := range task.Containers {
+		for _, binding := range container.NetworkBindings {
+			hostPorts = append(hostPorts, strconv.FormatInt(binding.HostPort, 10))
+		}
+	}
+
+	task.TargetHealth = []TaskTargetHealth{}
+	for _, targetGroup := range targetGroups {
+		for _, target := range targetGroup.Targets {
+			var matched bool
+			switch targetGroup.TargetType {
+			case elbv2.TargetTypeEnumIp:
+				matched = containsString(addresses, target.TargetId)
+			case elbv2.TargetTypeEnumInstance:
+				matched = instanceId != "" && target.TargetId == instanceId &&
+					containsString(hostPorts, strconv.FormatInt(target.Port, 10))
+			}
+
+			if matched {
+				task.TargetHealth = append(task.TargetHealth, TaskTargetHealth{
+					TargetGroupArn: targetGroup.TargetGroupArn,
+					TargetId:       target.TargetId,
+					Port:           target.Port,
+					State:          target.State,
+					Reason:         target.Reason,
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package awslambda
+
+import (
+	"errors"
+
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/hyperpilotio/ingestor/capturer/awscommon"
+	"github.com/hyperpilotio/ingestor/database"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+const defaultTableName = "functions"
+
+type VpcConfig struct {
+	VpcId            string   `json:"VpcId" bson:"VpcId"`
+	SubnetIds        []string `json:"SubnetIds" bson:"SubnetIds"`
+	SecurityGroupIds []string `json:"SecurityGroupIds" bson:"SecurityGroupIds"`
+}
+
+type Layer struct {
+	Arn      string `json:"Arn" bson:"Arn"`
+	CodeSize int64  `json:"CodeSize" bson:"CodeSize"`
+}
+
+type Alias struct {
+	Name            string `json:"Name" bson:"Name"`
+	AliasArn        string `json:"AliasArn" bson:"AliasArn"`
+	FunctionVersion string `json:"FunctionVersion" bson:"FunctionVersion"`
+	Description     string `json:"Description" bson:"Description"`
+}
+
+type Version struct {
+	Version      string `json:"Version" bson:"Version"`
+	FunctionArn  string `json:"FunctionArn" bson:"FunctionArn"`
+	CodeSha256   string `json:"CodeSha256" bson:"CodeSha256"`
+	LastModified string `json:"LastModified" bson:"LastModified"`
+}
+
+type Function struct {
+	FunctionName                 string     `json:"FunctionName" bson:"FunctionName"`
+	FunctionArn                  string     `json:"FunctionArn" bson:"FunctionArn"`
+	Runtime                      string     `json:"Runtime" bson:"Runtime"`
+	Handler                      string     `json:"Handler" bson:"Handler"`
+	MemorySize                   int64      `json:"MemorySize" bson:"MemorySize"`
+	Timeout                      int64      `json:"Timeout" bson:"Timeout"`
+	Role                         string     `json:"Role" bson:"Role"`
+	CodeSize                     int64      `json:"CodeSize" bson:"CodeSize"`
+	LastModified                 string     `json:"LastModified" bson:"LastModified"`
+	VpcConfig                    *VpcConfig `json:"VpcConfig" bson:"VpcConfig"`
+	Layers                       []Layer    `json:"Layers" bson:"Layers"`
+	Aliases                      []Alias    `json:"Aliases" bson:"Aliases"`
+	Versions                     []Version  `json:"Versions" bson:"Versions"`
+	ReservedConcurrentExecutions *int64     `json:"ReservedConcurrentExecutions" bson:"ReservedConcurrentExecutions"`
+}
+
+type Inventory struct {
+	ID                bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Region            string        `json:"Region" bson:"Region"`
+	Functions         []Function    `json:"Functions" bson:"Functions"`
+	ThrottledRequests int64         `json:"ThrottledRequests" bson:"ThrottledRequests"`
+}
+
+type AWSLambdaCapturer struct {
+	Region    string
+	Sess      *session.Session
+	DB        *database.MongoDB
+	Throttles *awscommon.ThrottleCounter
+}
+
+func NewCapturer(config *viper.Viper, region string) (*AWSLambdaCapturer, error) {
+	db, dbErr := database.NewDB(config)
+	if dbErr != nil {
+		return nil, dbErr
+	}
+
+	db.TableName = config.GetString("lambda.tableName")
+	if db.TableName == "" {
+		db.TableName = defaultTableName
+	}
+
+	session, err := awscommon.NewSession(config, region)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AWSLambdaCapturer{
+		Region:    region,
+		Sess:      session,
+		DB:        db,
+		Throttles: awscommon.NewThrottleCounter(session),
+	}, nil
+}
+
+func (capturer AWSLambdaCapturer) Capture() error {
+	inventory, err := capturer.GetFunctions()
+	if err != nil {
+		return errors.New("Unable to get functions info: " + err.Error())
+	}
+
+	selector := bson.M{"Region": capturer.Region}
+	if err := capturer.DB.Upsert(selector, *inventory); err != nil {
+		return errors.New("Unable to write functions info: " + err.Error())
+	}
+
+	return nil
+}
+
+func (capturer AWSLambdaCapturer) GetFunctions() (*Inventory, error) {
+	glog.V(1).Infof("GetFunctions for region: %s", capturer.Region)
+
+	lambdaSvc := lambda.New(capturer.Sess)
+
+	configurations := []*lambda.FunctionConfiguration{}
+	err := lambdaSvc.ListFunctionsPages(&lambda.ListFunctionsInput{}, func(page *lambda.ListFunctionsOutput, lastPage bool) bool {
+		configurations = append(configurations, page.Functions...)
+		return true
+	})
+	if err != nil {
+		return nil, errors.New("Unable to list functions: " + err.Error())
+	}
+
+	functions := []Function{}
+	for _, configuration := range configurations {
+		function := newFunction(configuration)
+		if err := describeFunction(lambdaSvc, function); err != nil {
+			return nil, err
+		}
+		functions = append(functions, *function)
+	}
+
+	inventory := &Inventory{
+		Region:    capturer.Region,
+		Functions: functions,
+	}
+
+	inventory.ThrottledRequests = capturer.Throttles.Reset()
+	if inventory.ThrottledRequests > 0 {
+		glog.Warningf("%d requests throttled while capturing functions of region %s", inventory.ThrottledRequests, capturer.Region)
+	}
+
+	return inventory, nil
+}
+
+func newFunction(configuration *lambda.FunctionConfiguration) *Function {
+	function := &Function{}
+	function.FunctionName = aws.StringValue(configuration.FunctionName)
+	function.FunctionArn = aws.StringValue(configuration.FunctionArn)
+	function.Runtime = aws.StringValue(configuration.Runtime)
+	function.Handler = aws.StringValue(configuration.Handler)
+	function.MemorySize = aws.Int64Value(configuration.MemorySize)
+	function.Timeout = aws.Int64Value(configuration.Timeout)
+	function.Role = aws.StringValue(configuration.Role)
+	function.CodeSize = aws.Int64Value(configuration.CodeSize)
+	function.LastModified = aws.StringValue(configuration.LastModified)
+
+	// functions outside a VPC come back with an empty VpcConfig
+	if vpcConfig := configuration.VpcConfig; vpcConfig != nil && aws.StringValue(vpcConfig.VpcId) != "" {
+		function.VpcConfig = &VpcConfig{
+			VpcId:            aws.StringValue(vpcConfig.VpcId),
+			SubnetIds:        aws.StringValueSlice(vpcConfig.SubnetIds),
+			SecurityGroupIds: aws.StringValueSlice(vpcConfig.SecurityGroupIds),
+		}
+	}
+
+	layers := []Layer{}
+	for _, layer := range configuration.Layers {
+		layers = append(layers, Layer{
+			Arn:      aws.StringValue(layer.Arn),
+			CodeSize: aws.Int64Value(layer.CodeSize),
+		})
+	}
+	function.Layers = layers
+
+	return function
+}
+
+// describeFunction adds the aliases, published versions and reserved
+// concurrency of the function.
+func describeFunction(lambdaSvc *lambda.Lambda, function *Function) error {
+	aliases := []Alias{}
+	listAliasesInput := &lambda.ListAliasesInput{
+		FunctionName: aws.String(function.FunctionName),
+	}
+	err := lambdaSvc.ListAliasesPages(listAliasesInput, func(page *lambda.ListAliasesOutput, lastPage bool) bool {
+		for _, alias := range page.Aliases {
+			aliases = append(aliases, Alias{
+				Name:            aws.StringValue(alias.Name),
+				AliasArn:        aws.StringValue(alias.AliasArn),
+				FunctionVersion: aws.StringValue(alias.FunctionVersion),
+				Description:     aws.StringValue(alias.Description),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return errors.New("Unable to list aliases of " + function.FunctionName + ": " + err.Error())
+	}
+	function.Aliases = aliases
+
+	versions := []Version{}
+	listVersionsInput := &lambda.ListVersionsByFunctionInput{
+		FunctionName: aws.String(function.FunctionName),
+	}
+	err = lambdaSvc.ListVersionsByFunctionPages(listVersionsInput, func(page *lambda.ListVersionsByFunctionOutput, lastPage bool) bool {
+		for _, version := range page.Versions {
+			versions = append(versions, Version{
+				Version:      aws.StringValue(version.Version),
+				FunctionArn:  aws.StringValue(version.FunctionArn),
+				CodeSha256:   aws.StringValue(version.CodeSha256),
+				LastModified: aws.StringValue(version.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return errors.New("Unable to list versions of " + function.FunctionName + ": " + err.Error())
+	}
+	function.Versions = versions
+
+	getFunctionInput := &lambda.GetFunctionInput{
+		FunctionName: aws.String(function.FunctionName),
+	}
+	getFunctionOutput, err := lambdaSvc.GetFunction(getFunctionInput)
+	if err != nil {
+		return errors.New("Unable to get function " + function.FunctionName + ": " + err.Error())
+	}
+	if getFunctionOutput.Concurrency != nil {
+		function.ReservedConcurrentExecutions = getFunctionOutput.Concurrency.ReservedConcurrentExecutions
+	}
+
+	return nil
+}
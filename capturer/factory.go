@@ -2,8 +2,15 @@ package capturer
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/golang/glog"
+
+	"github.com/hyperpilotio/ingestor/capturer/awscloudwatch"
+	"github.com/hyperpilotio/ingestor/capturer/awsec2"
 	"github.com/hyperpilotio/ingestor/capturer/awsecs"
+	"github.com/hyperpilotio/ingestor/capturer/awslambda"
 	"github.com/hyperpilotio/ingestor/capturer/kubernetes"
 	"github.com/spf13/viper"
 )
@@ -12,22 +19,44 @@ type Capturer interface {
 	Capture() error
 }
 
+// Stopper is implemented by capturers holding resources between captures
+type Stopper interface {
+	Stop()
+}
+
 type Capturers struct {
 	CapturerList []Capturer
 }
 
+// Run runs every capturer once. A failing capturer is logged and does not
+// prevent the others from capturing, the error reports how many failed.
 func (capturers *Capturers) Run() error {
 	// TODO: Each capturer has its own schedule, we eventually will need to figure out
 	// another way to run all of them.
+	failed := 0
 	for _, capturer := range capturers.CapturerList {
 		if err := capturer.Capture(); err != nil {
-			return err
+			glog.Warningf("Capturer %T failed: %s", capturer, err.Error())
+			failed++
 		}
 	}
 
+	if failed > 0 {
+		return fmt.Errorf("%d of %d capturers failed", failed, len(capturers.CapturerList))
+	}
+
 	return nil
 }
 
+// Stop releases the resources held by the capturers
+func (capturers *Capturers) Stop() {
+	for _, capturer := range capturers.CapturerList {
+		if stopper, ok := capturer.(Stopper); ok {
+			stopper.Stop()
+		}
+	}
+}
+
 func NewCapturers(config *viper.Viper) (*Capturers, error) {
 	capturers := &Capturers{
 		CapturerList: make([]Capturer, 0),
@@ -42,16 +71,64 @@ func NewCapturers(config *viper.Viper) (*Capturers, error) {
 			}
 			capturers.CapturerList = append(capturers.CapturerList, capturer)
 		}
+
+		if aws.Sub("cloudwatch") != nil {
+			interval, err := time.ParseDuration(config.GetString("interval"))
+			if err != nil {
+				return nil, errors.New("Unable to parse interval: " + err.Error())
+			}
+
+			for _, region := range aws.GetStringSlice("regions") {
+				capturer, err := awscloudwatch.NewCapturer(aws, region, interval)
+				if err != nil {
+					return nil, errors.New("Unable to create AWS CloudWatch capturer: " + err.Error())
+				}
+				capturers.CapturerList = append(capturers.CapturerList, capturer)
+			}
+		}
+
+		if aws.Sub("ec2") != nil {
+			for _, region := range aws.GetStringSlice("regions") {
+				capturer, err := awsec2.NewCapturer(aws, region)
+				if err != nil {
+					return nil, errors.New("Unable to create AWS EC2 capturer: " + err.Error())
+				}
+				capturers.CapturerList = append(capturers.CapturerList, capturer)
+			}
+		}
+
+		if aws.Sub("lambda") != nil {
+			for _, region := range aws.GetStringSlice("regions") {
+				capturer, err := awslambda.NewCapturer(aws, region)
+				if err != nil {
+					return nil, errors.New("Unable to create AWS Lambda capturer: " + err.Error())
+				}
+				capturers.CapturerList = append(capturers.CapturerList, capturer)
+			}
+		}
 	}
 
 	k8sConfig := config.Sub("kubernetes")
 	if k8sConfig != nil {
-		configPath := k8sConfig.GetString("configPath")
-		capturer, err := kubernetes.NewCapturer(configPath)
+		clusterConfigs, err := kubernetes.ClusterConfigs(k8sConfig)
 		if err != nil {
-			return nil, errors.New("Unable to create Kubernetes capturer: " + err.Error())
+			return nil, errors.New("Unable to read Kubernetes clusters: " + err.Error())
+		}
+
+		// snapshots are upserted by cluster id, two entries reaching the same
+		// API server would overwrite each other's snapshot
+		clusterNames := make(map[string]string)
+		for _, clusterConfig := range clusterConfigs {
+			capturer, err := kubernetes.NewCapturer(k8sConfig, clusterConfig)
+			if err != nil {
+				return nil, errors.New("Unable to create Kubernetes capturer for " + clusterConfig.Name + ": " + err.Error())
+			}
+			if name, ok := clusterNames[capturer.ClusterID]; ok {
+				return nil, errors.New("Kubernetes clusters " + name + " and " + clusterConfig.Name + " reach the same API server")
+			}
+			clusterNames[capturer.ClusterID] = clusterConfig.Name
+			capturers.CapturerList = append(capturers.CapturerList, capturer)
 		}
-		capturers.CapturerList = append(capturers.CapturerList, capturer)
 	}
 
 	return capturers, nil
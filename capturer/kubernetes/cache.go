@@ -0,0 +1,297 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	batchv1beta1listers "k8s.io/client-go/listers/batch/v1beta1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// cacheSyncTimeout bounds how long a capture waits for the informers' initial
+// list, informers still listing keep running and are checked again by the
+// next capture
+const cacheSyncTimeout = 30 * time.Second
+
+// clusterCache keeps a local copy of the captured objects up to date through
+// shared informers, so captures read from memory instead of listing the API.
+// Namespaced objects are watched through a second factory filtered by the
+// cluster's selectors, nodes and namespaces are always watched in full. Pods
+// are watched in full too as node totals count every pod, the selectors are
+// matched against them in memory.
+// Stopped informers cannot be started again, so every start builds new ones.
+// Workload kinds other than deployments are optional, the ones the API server
+// doesn't serve or the ingestor may not watch are skipped and read as empty.
+type clusterCache struct {
+	clusterName      string
+	clientset        kubernetes.Interface
+	namespace        string
+	tweakListOptions func(*metav1.ListOptions)
+	labelSelector    labels.Selector
+	fieldSelector    fields.Selector
+
+	deployments appslisters.DeploymentLister
+	nodes       corelisters.NodeLister
+	namespaces  corelisters.NamespaceLister
+	pods        corelisters.PodLister
+	services    corelisters.ServiceLister
+
+	statefulSets appslisters.StatefulSetLister
+	daemonSets   appslisters.DaemonSetLister
+	replicaSets  appslisters.ReplicaSetLister
+	jobs         batchlisters.JobLister
+	cronJobs     batchv1beta1listers.CronJobLister
+
+	informersSynced []cache.InformerSynced
+	synced          bool
+	stopCh          chan struct{}
+}
+
+func newClusterCache(clientset kubernetes.Interface, clusterConfig ClusterConfig) (*clusterCache, error) {
+	labelSelector, err := labels.Parse(clusterConfig.LabelSelector)
+	if err != nil {
+		return nil, errors.New("Unable to parse label selector: " + err.Error())
+	}
+
+	// excluded namespaces are dropped by the API server, or by matchesPod for
+	// pods, only a list of included namespaces has to be filtered in memory
+	fieldSelectors := []string{}
+	if clusterConfig.FieldSelector != "" {
+		fieldSelectors = append(fieldSelectors, clusterConfig.FieldSelector)
+	}
+	for _, namespace := range clusterConfig.ExcludeNamespaces {
+		fieldSelectors = append(fieldSelectors, "metadata.namespace!="+namespace)
+	}
+	fieldSelector := strings.Join(fieldSelectors, ",")
+	parsedFieldSelector, err := fields.ParseSelector(fieldSelector)
+	if err != nil {
+		return nil, errors.New("Unable to parse field selector: " + err.Error())
+	}
+
+	namespace := metav1.NamespaceAll
+	if len(clusterConfig.Namespaces) == 1 {
+		namespace = clusterConfig.Namespaces[0]
+	}
+
+	return &clusterCache{
+		clusterName: clusterConfig.Name,
+		clientset:   clientset,
+		namespace:   namespace,
+		tweakListOptions: func(options *metav1.ListOptions) {
+			options.LabelSelector = clusterConfig.LabelSelector
+			options.FieldSelector = fieldSelector
+		},
+		labelSelector: labelSelector,
+		fieldSelector: parsedFieldSelector,
+	}, nil
+}
+
+// start builds the informers and runs them in the background
+func (clusterCache *clusterCache) start() error {
+	// snapshots are read from the listers, resyncing would only replay events
+	factory := informers.NewSharedInformerFactory(clusterCache.clientset, 0)
+	namespacedFactory := informers.NewFilteredSharedInformerFactory(clusterCache.clientset, 0,
+		clusterCache.namespace, clusterCache.tweakListOptions)
+
+	deployments := namespacedFactory.Apps().V1().Deployments()
+	nodes := factory.Core().V1().Nodes()
+	namespaces := factory.Core().V1().Namespaces()
+	pods := factory.Core().V1().Pods()
+	services := namespacedFactory.Core().V1().Services()
+
+	// requesting an informer registers it with its factory
+	informersSynced := []cache.InformerSynced{
+		deployments.Informer().HasSynced,
+		nodes.Informer().HasSynced,
+		namespaces.Informer().HasSynced,
+		pods.Informer().HasSynced,
+		services.Informer().HasSynced,
+	}
+
+	statefulSets := appslisters.NewStatefulSetLister(newEmptyIndexer())
+	if available, err := clusterCache.available("apps", "v1", "statefulsets"); err != nil {
+		return err
+	} else if available {
+		informer := namespacedFactory.Apps().V1().StatefulSets()
+		informersSynced = append(informersSynced, informer.Informer().HasSynced)
+		statefulSets = informer.Lister()
+	}
+
+	daemonSets := appslisters.NewDaemonSetLister(newEmptyIndexer())
+	if available, err := clusterCache.available("apps", "v1", "daemonsets"); err != nil {
+		return err
+	} else if available {
+		informer := namespacedFactory.Apps().V1().DaemonSets()
+		informersSynced = append(informersSynced, informer.Informer().HasSynced)
+		daemonSets = informer.Lister()
+	}
+
+	replicaSets := appslisters.NewReplicaSetLister(newEmptyIndexer())
+	if available, err := clusterCache.available("apps", "v1", "replicasets"); err != nil {
+		return err
+	} else if available {
+		informer := namespacedFactory.Apps().V1().ReplicaSets()
+		informersSynced = append(informersSynced, informer.Informer().HasSynced)
+		replicaSets = informer.Lister()
+	}
+
+	jobs := batchlisters.NewJobLister(newEmptyIndexer())
+	if available, err := clusterCache.available("batch", "v1", "jobs"); err != nil {
+		return err
+	} else if available {
+		informer := namespacedFactory.Batch().V1().Jobs()
+		informersSynced = append(informersSynced, informer.Informer().HasSynced)
+		jobs = informer.Lister()
+	}
+
+	cronJobs := batchv1beta1listers.NewCronJobLister(newEmptyIndexer())
+	if available, err := clusterCache.available("batch", "v1beta1", "cronjobs"); err != nil {
+		return err
+	} else if available {
+		informer := namespacedFactory.Batch().V1beta1().CronJobs()
+		informersSynced = append(informersSynced, informer.Informer().HasSynced)
+		cronJobs = informer.Lister()
+	}
+
+	clusterCache.informersSynced = informersSynced
+	clusterCache.deployments = deployments.Lister()
+	clusterCache.nodes = nodes.Lister()
+	clusterCache.namespaces = namespaces.Lister()
+	clusterCache.pods = pods.Lister()
+	clusterCache.services = services.Lister()
+	clusterCache.statefulSets = statefulSets
+	clusterCache.daemonSets = daemonSets
+	clusterCache.replicaSets = replicaSets
+	clusterCache.jobs = jobs
+	clusterCache.cronJobs = cronJobs
+
+	clusterCache.synced = false
+	clusterCache.stopCh = make(chan struct{})
+	factory.Start(clusterCache.stopCh)
+	namespacedFactory.Start(clusterCache.stopCh)
+
+	return nil
+}
+
+// available returns whether the API server serves the resource and the
+// ingestor may list and watch it. An informer of an unavailable kind would
+// never sync and hold back every capture of the cluster.
+func (clusterCache *clusterCache) available(group string, version string, resource string) (bool, error) {
+	groupVersion := schema.GroupVersion{Group: group, Version: version}.String()
+	resources, err := clusterCache.clientset.Discovery().ServerResourcesForGroupVersion(groupVersion)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, errors.New("Unable to discover " + groupVersion + " resources: " + err.Error())
+	}
+
+	served := false
+	if err == nil {
+		for _, apiResource := range resources.APIResources {
+			if apiResource.Name == resource {
+				served = true
+			}
+		}
+	}
+	if !served {
+		glog.Warningf("%s %s are not served by cluster %s and are not captured", groupVersion, resource, clusterCache.clusterName)
+		return false, nil
+	}
+
+	for _, verb := range []string{"list", "watch"} {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: clusterCache.namespace,
+					Verb:      verb,
+					Group:     group,
+					Version:   version,
+					Resource:  resource,
+				},
+			},
+		}
+		response, err := clusterCache.clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(review)
+		if err != nil {
+			return false, errors.New("Unable to review access to " + resource + ": " + err.Error())
+		}
+		if !response.Status.Allowed {
+			glog.Warningf("Not allowed to %s %s %s of cluster %s, they are not captured", verb, groupVersion, resource, clusterCache.clusterName)
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// matchesPod applies the cluster's selectors to a pod, the API server applies
+// them to the other namespaced kinds
+func (clusterCache *clusterCache) matchesPod(pod *v1.Pod) bool {
+	return clusterCache.labelSelector.Matches(labels.Set(pod.Labels)) &&
+		clusterCache.fieldSelector.Matches(podFields(pod))
+}
+
+// podFields are the pod fields the API server accepts in field selectors
+func podFields(pod *v1.Pod) fields.Set {
+	return fields.Set{
+		"metadata.name":           pod.Name,
+		"metadata.namespace":      pod.Namespace,
+		"spec.nodeName":           pod.Spec.NodeName,
+		"spec.restartPolicy":      string(pod.Spec.RestartPolicy),
+		"spec.schedulerName":      pod.Spec.SchedulerName,
+		"spec.serviceAccountName": pod.Spec.ServiceAccountName,
+		"status.phase":            string(pod.Status.Phase),
+		"status.podIP":            pod.Status.PodIP,
+	}
+}
+
+// newEmptyIndexer backs the listers of skipped kinds, they list nothing
+func newEmptyIndexer() cache.Indexer {
+	return cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+}
+
+// waitForSync waits up to cacheSyncTimeout for the informers' initial list,
+// once complete the informers stay synced until stopped
+func (clusterCache *clusterCache) waitForSync() error {
+	if clusterCache.synced {
+		return nil
+	}
+
+	// a separate channel aborts the wait without stopping the informers
+	timeoutCh := make(chan struct{})
+	timer := time.AfterFunc(cacheSyncTimeout, func() { close(timeoutCh) })
+	defer timer.Stop()
+
+	if !cache.WaitForCacheSync(timeoutCh, clusterCache.informersSynced...) {
+		return fmt.Errorf("informers not synced within %s, still listing", cacheSyncTimeout)
+	}
+	clusterCache.synced = true
+	glog.V(1).Infof("Kubernetes cluster cache synced")
+
+	return nil
+}
+
+func (clusterCache *clusterCache) started() bool {
+	return clusterCache.stopCh != nil
+}
+
+func (clusterCache *clusterCache) stop() {
+	if clusterCache.stopCh != nil {
+		close(clusterCache.stopCh)
+		clusterCache.stopCh = nil
+		clusterCache.synced = false
+	}
+}
@@ -1,167 +1,662 @@
 package kubernetes
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
 	"gopkg.in/mgo.v2/bson"
 
+	"github.com/hyperpilotio/ingestor/database"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+// defaultNodeAnnotations are the node annotations recorded unless
+// kubernetes.nodeAnnotations lists others
+var defaultNodeAnnotations = []string{
+	"cluster-autoscaler.kubernetes.io/scale-down-disabled",
+	"node.alpha.kubernetes.io/ttl",
+	"volumes.kubernetes.io/controller-managed-attach-detach",
+}
+
 type KubernetesCapturer struct {
-	config *rest.Config
+	ClusterName     string
+	ClusterID       string
+	DB              *database.MongoDB
+	WriteOnChange   bool
+	Namespaces      []string
+	NodeAnnotations []string
+	config          *rest.Config
+	cache           *clusterCache
+	lastHash        string
+}
+
+// ClusterConfig is an entry of kubernetes.clusters, Context selects a context
+// of the kubeconfig other than its current one. Without ConfigPath and Context
+// the in-cluster service account config is used. Namespaces, ExcludeNamespaces
+// and the selectors restrict the namespaced objects captured, nodes are always
+// captured in full.
+type ClusterConfig struct {
+	Name              string   `mapstructure:"name"`
+	ConfigPath        string   `mapstructure:"configPath"`
+	Context           string   `mapstructure:"context"`
+	QPS               float32  `mapstructure:"qps"`
+	Burst             int      `mapstructure:"burst"`
+	Impersonate       string   `mapstructure:"impersonate"`
+	Namespaces        []string `mapstructure:"namespaces"`
+	ExcludeNamespaces []string `mapstructure:"excludeNamespaces"`
+	LabelSelector     string   `mapstructure:"labelSelector"`
+	FieldSelector     string   `mapstructure:"fieldSelector"`
 }
 
+// InCluster returns whether the cluster is reached with the in-cluster config
+func (clusterConfig ClusterConfig) InCluster() bool {
+	return clusterConfig.ConfigPath == "" && clusterConfig.Context == ""
+}
+
+// KubernetesContainer is a container of a pod or pod template, the status
+// fields are only set for containers of pods.
 type KubernetesContainer struct {
-	CantainerName  string            `json:"CantainerName" bson:"CantainerName"`
-	ContainerImage string            `json:"ContainerImage" bson:"ContainerImage"`
-	Limit          map[string]string `json:"Limit" bson:"Limit"`
+	CantainerName           string              `json:"CantainerName" bson:"CantainerName"`
+	ContainerImage          string              `json:"ContainerImage" bson:"ContainerImage"`
+	ImageID                 string              `json:"ImageID" bson:"ImageID"`
+	Requests                KubernetesResources `json:"Requests" bson:"Requests"`
+	Limits                  KubernetesResources `json:"Limits" bson:"Limits"`
+	Ready                   bool                `json:"Ready" bson:"Ready"`
+	RestartCount            int32               `json:"RestartCount" bson:"RestartCount"`
+	State                   string              `json:"State" bson:"State"`
+	StateReason             string              `json:"StateReason" bson:"StateReason"`
+	LastTerminationReason   string              `json:"LastTerminationReason" bson:"LastTerminationReason"`
+	LastTerminationExitCode int32               `json:"LastTerminationExitCode" bson:"LastTerminationExitCode"`
 }
 
 type KubernetesPod struct {
-	PodName     string                `json:"PodName" bson:"PodName"`
-	NodeName    string                `json:"NodeName" bson:"NodeName"`
-	ClusterName string                `json:"ClusterName" bson:"ClusterName"`
-	Phase       string                `json:"Phase" bson:"Phase"`
-	Containers  []KubernetesContainer `json:"Containers" bson:"Containers"`
+	PodName           string                `json:"PodName" bson:"PodName"`
+	Namespace         string                `json:"Namespace" bson:"Namespace"`
+	NodeName          string                `json:"NodeName" bson:"NodeName"`
+	ClusterName       string                `json:"ClusterName" bson:"ClusterName"`
+	Labels            map[string]string     `json:"Labels" bson:"Labels"`
+	Phase             string                `json:"Phase" bson:"Phase"`
+	PodIP             string                `json:"PodIP" bson:"PodIP"`
+	HostIP            string                `json:"HostIP" bson:"HostIP"`
+	QOSClass          string                `json:"QOSClass" bson:"QOSClass"`
+	StartTime         time.Time             `json:"StartTime" bson:"StartTime"`
+	Conditions        []v1.PodCondition     `json:"Conditions" bson:"Conditions"`
+	InitContainers    []KubernetesContainer `json:"InitContainers" bson:"InitContainers"`
+	Tolerations       []v1.Toleration       `json:"Tolerations" bson:"Tolerations"`
+	Affinity          *v1.Affinity          `json:"Affinity" bson:"Affinity"`
+	PriorityClassName string                `json:"PriorityClassName" bson:"PriorityClassName"`
+	Priority          int32                 `json:"Priority" bson:"Priority"`
+	OwnerKind         string                `json:"OwnerKind" bson:"OwnerKind"`
+	OwnerName         string                `json:"OwnerName" bson:"OwnerName"`
+	OwnerNamespace    string                `json:"OwnerNamespace" bson:"OwnerNamespace"`
+	Containers        []KubernetesContainer `json:"Containers" bson:"Containers"`
+	Requests          KubernetesResources   `json:"Requests" bson:"Requests"`
+	Limits            KubernetesResources   `json:"Limits" bson:"Limits"`
 }
 
 type KubernetesNode struct {
-	IsMaster   bool               `json:"IsMaster" bson:"IsMaster"`
-	NodeName   string             `json:"NodeName" bson:"NodeName"`
-	Pods       []KubernetesPod    `json:"Pods" bson:"Pods"`
-	Conditions []v1.NodeCondition `json:"Conditions" bson:"Conditions"`
+	IsMaster                bool                `json:"IsMaster" bson:"IsMaster"`
+	NodeName                string              `json:"NodeName" bson:"NodeName"`
+	Pods                    []KubernetesPod     `json:"Pods" bson:"Pods"`
+	Conditions              []v1.NodeCondition  `json:"Conditions" bson:"Conditions"`
+	Labels                  map[string]string   `json:"Labels" bson:"Labels"`
+	Annotations             map[string]string   `json:"Annotations" bson:"Annotations"`
+	Taints                  []v1.Taint          `json:"Taints" bson:"Taints"`
+	Unschedulable           bool                `json:"Unschedulable" bson:"Unschedulable"`
+	Addresses               []v1.NodeAddress    `json:"Addresses" bson:"Addresses"`
+	ProviderID              string              `json:"ProviderID" bson:"ProviderID"`
+	KubeletVersion          string              `json:"KubeletVersion" bson:"KubeletVersion"`
+	ContainerRuntimeVersion string              `json:"ContainerRuntimeVersion" bson:"ContainerRuntimeVersion"`
+	KernelVersion           string              `json:"KernelVersion" bson:"KernelVersion"`
+	OSImage                 string              `json:"OSImage" bson:"OSImage"`
+	OperatingSystem         string              `json:"OperatingSystem" bson:"OperatingSystem"`
+	Architecture            string              `json:"Architecture" bson:"Architecture"`
+	CreationTime            time.Time           `json:"CreationTime" bson:"CreationTime"`
+	Capacity                KubernetesResources `json:"Capacity" bson:"Capacity"`
+	Allocatable             KubernetesResources `json:"Allocatable" bson:"Allocatable"`
+	Requests                KubernetesResources `json:"Requests" bson:"Requests"`
+	Limits                  KubernetesResources `json:"Limits" bson:"Limits"`
 }
 
 type KubernetesService struct {
-	ServiceName string           `json:"ServiceName" bson:"ServiceName"`
-	ClusterIP   string           `json:"ClusterIP" bson:"ClusterIP"`
-	ExternalIPs []string         `json:"ExternalIPs" bson:"ExternalIPs"`
-	Ports       []v1.ServicePort `json:"Ports" bson:"Ports"`
+	ServiceName         string                   `json:"ServiceName" bson:"ServiceName"`
+	Namespace           string                   `json:"Namespace" bson:"Namespace"`
+	Type                string                   `json:"Type" bson:"Type"`
+	ClusterIP           string                   `json:"ClusterIP" bson:"ClusterIP"`
+	ExternalIPs         []string                 `json:"ExternalIPs" bson:"ExternalIPs"`
+	Ports               []v1.ServicePort         `json:"Ports" bson:"Ports"`
+	Selector            map[string]string        `json:"Selector" bson:"Selector"`
+	SessionAffinity     string                   `json:"SessionAffinity" bson:"SessionAffinity"`
+	LoadBalancerIngress []v1.LoadBalancerIngress `json:"LoadBalancerIngress" bson:"LoadBalancerIngress"`
 }
 
 type KubernetesDeployment struct {
-	Name         string            `json:"Name" bson:"Name"`
-	Namespace    string            `json:"Namespace" bson:"Namespace"`
-	SelfLink     string            `json:"SelfLink" bson:"SelfLink"`
-	Replicas     int32             `json:"Replicas" bson:"Replicas"`
-	Labels       map[string]string `json:"Labels" bson:"Labels"`
-	Selector     map[string]string `json:"Selector" bson:"Selector"`
-	NodeSelector map[string]string `json:"NodeSelector" bson:"NodeSelector"`
+	Name              string                `json:"Name" bson:"Name"`
+	Namespace         string                `json:"Namespace" bson:"Namespace"`
+	SelfLink          string                `json:"SelfLink" bson:"SelfLink"`
+	Replicas          int32                 `json:"Replicas" bson:"Replicas"`
+	Labels            map[string]string     `json:"Labels" bson:"Labels"`
+	Selector          map[string]string     `json:"Selector" bson:"Selector"`
+	NodeSelector      map[string]string     `json:"NodeSelector" bson:"NodeSelector"`
+	Containers        []KubernetesContainer `json:"Containers" bson:"Containers"`
+	UpdateStrategy    string                `json:"UpdateStrategy" bson:"UpdateStrategy"`
+	ReadyReplicas     int32                 `json:"ReadyReplicas" bson:"ReadyReplicas"`
+	UpdatedReplicas   int32                 `json:"UpdatedReplicas" bson:"UpdatedReplicas"`
+	AvailableReplicas int32                 `json:"AvailableReplicas" bson:"AvailableReplicas"`
+	Pods              []string              `json:"Pods" bson:"Pods"`
 }
 
 type KubernetesCluster struct {
-	ClusterName string                 `json:"ClusterName" bson:"ClusterName"`
-	ID          bson.ObjectId          `json:"id" bson:"_id,omitempty"`
-	Nodes       []KubernetesNode       `json:"Nodes" bson:"Nodes"`
-	Services    []KubernetesService    `json:"Services" bson:"Services"`
-	Deployments []KubernetesDeployment `json:"Deployments" bson:"Deployments"`
+	ClusterName     string                  `json:"ClusterName" bson:"ClusterName"`
+	ClusterID       string                  `json:"ClusterID" bson:"ClusterID"`
+	Server          string                  `json:"Server" bson:"Server"`
+	ID              bson.ObjectId           `json:"id" bson:"_id,omitempty"`
+	Nodes           []KubernetesNode        `json:"Nodes" bson:"Nodes"`
+	UnscheduledPods []KubernetesPod         `json:"UnscheduledPods" bson:"UnscheduledPods"`
+	Services        []KubernetesService     `json:"Services" bson:"Services"`
+	Deployments     []KubernetesDeployment  `json:"Deployments" bson:"Deployments"`
+	StatefulSets    []KubernetesStatefulSet `json:"StatefulSets" bson:"StatefulSets"`
+	DaemonSets      []KubernetesDaemonSet   `json:"DaemonSets" bson:"DaemonSets"`
+	ReplicaSets     []KubernetesReplicaSet  `json:"ReplicaSets" bson:"ReplicaSets"`
+	Jobs            []KubernetesJob         `json:"Jobs" bson:"Jobs"`
+	CronJobs        []KubernetesCronJob     `json:"CronJobs" bson:"CronJobs"`
 }
 
-type K8sDeployments struct {
-	ID       bson.ObjectId       `json:"id" bson:"_id,omitempty"`
-	Clusters []KubernetesCluster `json:"Clusters" bson:"Clusters"`
+// ClusterConfigs reads the clusters to capture from kubernetes.clusters, a
+// single kubernetes.configPath is still accepted as a cluster named default.
+// When neither is set and the ingestor runs in a pod, its own cluster is
+// captured as kubernetes.clusterName.
+func ClusterConfigs(config *viper.Viper) ([]ClusterConfig, error) {
+	clusterConfigs := []ClusterConfig{}
+	if err := config.UnmarshalKey("clusters", &clusterConfigs); err != nil {
+		return nil, errors.New("Unable to parse clusters: " + err.Error())
+	}
+
+	if len(clusterConfigs) == 0 {
+		if config.GetString("configPath") != "" {
+			clusterConfigs = append(clusterConfigs, ClusterConfig{
+				Name:       "default",
+				ConfigPath: config.GetString("configPath"),
+			})
+		} else if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+			clusterName := config.GetString("clusterName")
+			if clusterName == "" {
+				clusterName = "in-cluster"
+			}
+			clusterConfigs = append(clusterConfigs, ClusterConfig{
+				Name: clusterName,
+			})
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, clusterConfig := range clusterConfigs {
+		if clusterConfig.Name == "" {
+			clusterConfigs[i].Name = clusterConfig.Context
+		}
+		if clusterConfigs[i].Name == "" {
+			return nil, errors.New("Kubernetes cluster name is required when no context is given")
+		}
+		if names[clusterConfigs[i].Name] {
+			return nil, errors.New("Duplicate Kubernetes cluster name: " + clusterConfigs[i].Name)
+		}
+		names[clusterConfigs[i].Name] = true
+	}
+
+	return clusterConfigs, nil
 }
 
-func NewCapturer(kubeconfigPath string) (*KubernetesCapturer, error) {
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+func NewCapturer(config *viper.Viper, clusterConfig ClusterConfig) (*KubernetesCapturer, error) {
+	db, dbErr := database.NewDB(config)
+	if dbErr != nil {
+		return nil, dbErr
+	}
+
+	restConfig, err := newRestConfig(clusterConfig)
 	if err != nil {
 		return nil, errors.New("Unable to build config: " + err.Error())
 	}
 
+	// the in-cluster API server address is the same in every cluster, so the
+	// configured name is the only identity available
+	identity := restConfig.Host
+	if clusterConfig.InCluster() {
+		identity = clusterConfig.Name + "@" + restConfig.Host
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.New("Unable to create a new Clientset: " + err.Error())
+	}
+
+	nodeAnnotations := config.GetStringSlice("nodeAnnotations")
+	if len(nodeAnnotations) == 0 {
+		nodeAnnotations = defaultNodeAnnotations
+	}
+
+	cache, err := newClusterCache(clientset, clusterConfig)
+	if err != nil {
+		return nil, errors.New("Unable to create cache of cluster " + clusterConfig.Name + ": " + err.Error())
+	}
+
 	return &KubernetesCapturer{
-		config: config,
+		ClusterName:     clusterConfig.Name,
+		ClusterID:       clusterID(identity),
+		DB:              db,
+		WriteOnChange:   config.GetBool("writeOnChange"),
+		NodeAnnotations: nodeAnnotations,
+		Namespaces:      clusterConfig.Namespaces,
+		config:          restConfig,
+		cache:           cache,
 	}, nil
 }
 
-func (capturer *KubernetesCapturer) Capture() error {
-	clientset, err := kubernetes.NewForConfig(capturer.config)
+func newRestConfig(clusterConfig ClusterConfig) (*rest.Config, error) {
+	var restConfig *rest.Config
+	var err error
+	if clusterConfig.InCluster() {
+		restConfig, err = rest.InClusterConfig()
+	} else {
+		// a context alone is looked up in the default kubeconfig files
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		if clusterConfig.ConfigPath != "" {
+			loadingRules.ExplicitPath = clusterConfig.ConfigPath
+		}
+		overrides := &clientcmd.ConfigOverrides{CurrentContext: clusterConfig.Context}
+		restConfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	}
 	if err != nil {
-		return errors.New("Unable to create a new Clientset: " + err.Error())
+		return nil, err
 	}
 
-	deployments, err := clientset.ExtensionsV1beta1().Deployments("").List(v1.ListOptions{})
+	if clusterConfig.QPS > 0 {
+		restConfig.QPS = clusterConfig.QPS
+	}
+	if clusterConfig.Burst > 0 {
+		restConfig.Burst = clusterConfig.Burst
+	}
+	if clusterConfig.Impersonate != "" {
+		restConfig.Impersonate = rest.ImpersonationConfig{UserName: clusterConfig.Impersonate}
+	}
+
+	return restConfig, nil
+}
+
+// clusterID derives a stable id from the API server address, so the cluster
+// keeps its identity when renamed in the config or reached via another context.
+func clusterID(server string) string {
+	hash := sha1.Sum([]byte(strings.TrimSuffix(strings.ToLower(server), "/")))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// Capture materializes a snapshot of the cluster from the informers' cache,
+// the informers are started on the first capture.
+func (capturer *KubernetesCapturer) Capture() error {
+	if !capturer.cache.started() {
+		if err := capturer.cache.start(); err != nil {
+			return errors.New("Unable to start cache of cluster " + capturer.ClusterName + ": " + err.Error())
+		}
+	}
+	if err := capturer.cache.waitForSync(); err != nil {
+		return errors.New("Unable to read cache of cluster " + capturer.ClusterName + ": " + err.Error())
+	}
+
+	deployments, err := capturer.cache.deployments.List(labels.Everything())
 	if err != nil {
 		return errors.New("Unable to find deployments: " + err.Error())
 	}
 
-	nodes, err := clientset.CoreV1().Nodes().List(v1.ListOptions{})
+	nodes, err := capturer.cache.nodes.List(labels.Everything())
 	if err != nil {
 		return errors.New("Unable to find nodes: " + err.Error())
 	}
 
-	pods, err := clientset.CoreV1().Pods("").List(v1.ListOptions{})
+	namespaces, err := capturer.cache.namespaces.List(labels.Everything())
+	if err != nil {
+		return errors.New("Unable to find namespaces: " + err.Error())
+	}
+
+	pods, err := capturer.cache.pods.List(labels.Everything())
 	if err != nil {
 		return errors.New("Unable to find pods: " + err.Error())
 	}
 
+	services, err := capturer.cache.services.List(labels.Everything())
+	if err != nil {
+		return errors.New("Unable to find services: " + err.Error())
+	}
+
+	statefulSets, err := capturer.cache.statefulSets.List(labels.Everything())
+	if err != nil {
+		return errors.New("Unable to find stateful sets: " + err.Error())
+	}
+
+	daemonSets, err := capturer.cache.daemonSets.List(labels.Everything())
+	if err != nil {
+		return errors.New("Unable to find daemon sets: " + err.Error())
+	}
+
+	replicaSets, err := capturer.cache.replicaSets.List(labels.Everything())
+	if err != nil {
+		return errors.New("Unable to find replica sets: " + err.Error())
+	}
+
+	jobs, err := capturer.cache.jobs.List(labels.Everything())
+	if err != nil {
+		return errors.New("Unable to find jobs: " + err.Error())
+	}
+
+	cronJobs, err := capturer.cache.cronJobs.List(labels.Everything())
+	if err != nil {
+		return errors.New("Unable to find cron jobs: " + err.Error())
+	}
+
+	sort.Slice(deployments, func(i, j int) bool { return lessObject(deployments[i], deployments[j]) })
+	sort.Slice(nodes, func(i, j int) bool { return lessObject(nodes[i], nodes[j]) })
+	sort.Slice(pods, func(i, j int) bool { return lessObject(pods[i], pods[j]) })
+	sort.Slice(services, func(i, j int) bool { return lessObject(services[i], services[j]) })
+	sort.Slice(statefulSets, func(i, j int) bool { return lessObject(statefulSets[i], statefulSets[j]) })
+	sort.Slice(daemonSets, func(i, j int) bool { return lessObject(daemonSets[i], daemonSets[j]) })
+	sort.Slice(replicaSets, func(i, j int) bool { return lessObject(replicaSets[i], replicaSets[j]) })
+	sort.Slice(jobs, func(i, j int) bool { return lessObject(jobs[i], jobs[j]) })
+	sort.Slice(cronJobs, func(i, j int) bool { return lessObject(cronJobs[i], cronJobs[j]) })
+
+	// pods are grouped and named on workloads in the order sorted above
+	graph := newOwnerGraph(replicaSets, jobs)
+	scope := newCaptureScope(capturer.Namespaces, graph)
+	for _, namespace := range namespaces {
+		scope.optOut("Namespace", namespace)
+	}
+	for _, deployment := range deployments {
+		scope.optOut("Deployment", deployment)
+	}
+	for _, statefulSet := range statefulSets {
+		scope.optOut("StatefulSet", statefulSet)
+	}
+	for _, daemonSet := range daemonSets {
+		scope.optOut("DaemonSet", daemonSet)
+	}
+	for _, replicaSet := range replicaSets {
+		scope.optOut("ReplicaSet", replicaSet)
+	}
+	for _, job := range jobs {
+		scope.optOut("Job", job)
+	}
+	for _, cronJob := range cronJobs {
+		scope.optOut("CronJob", cronJob)
+	}
+
+	// node totals count every pod holding the node's resources, including the
+	// pods left out of the capture's scope
+	nodeRequests := make(map[string][]KubernetesResources)
+	nodeLimits := make(map[string][]KubernetesResources)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		requests, limits := podResources(pod)
+		nodeRequests[pod.Spec.NodeName] = append(nodeRequests[pod.Spec.NodeName], requests)
+		nodeLimits[pod.Spec.NodeName] = append(nodeLimits[pod.Spec.NodeName], limits)
+	}
+
+	nodePods := make(map[string][]*v1.Pod)
+	for _, pod := range pods {
+		if !capturer.cache.matchesPod(pod) || !scope.includes(pod) {
+			continue
+		}
+		graph.addPod(pod)
+		nodePods[pod.Spec.NodeName] = append(nodePods[pod.Spec.NodeName], pod)
+	}
+
 	k8sCluster := &KubernetesCluster{}
+	k8sCluster.ClusterName = capturer.ClusterName
+	k8sCluster.ClusterID = capturer.ClusterID
+	k8sCluster.Server = capturer.config.Host
 	clusterDeployments := []KubernetesDeployment{}
-	for _, deployment := range deployments.Items {
+	for _, deployment := range deployments {
+		if !scope.includes(deployment) {
+			continue
+		}
 		clusterDeployment := &KubernetesDeployment{}
 		clusterDeployment.Name = deployment.Name
 		clusterDeployment.Namespace = deployment.Namespace
 		clusterDeployment.SelfLink = deployment.SelfLink
 		clusterDeployment.Labels = deployment.Labels
-		clusterDeployment.Replicas = *deployment.Spec.Replicas
-		clusterDeployment.Selector = deployment.Spec.Selector.MatchLabels
+		clusterDeployment.Replicas = int32Value(deployment.Spec.Replicas)
+		clusterDeployment.Selector = selectorLabels(deployment.Spec.Selector)
 		clusterDeployment.NodeSelector = deployment.Spec.Template.Spec.NodeSelector
+		clusterDeployment.Containers = templateContainers(deployment.Spec.Template.Spec)
+		clusterDeployment.UpdateStrategy = string(deployment.Spec.Strategy.Type)
+		clusterDeployment.ReadyReplicas = deployment.Status.ReadyReplicas
+		clusterDeployment.UpdatedReplicas = deployment.Status.UpdatedReplicas
+		clusterDeployment.AvailableReplicas = deployment.Status.AvailableReplicas
+		clusterDeployment.Pods = graph.podNames("Deployment", deployment.Namespace, deployment.Name)
 		clusterDeployments = append(clusterDeployments, *clusterDeployment)
 	}
 
 	k8sCluster.Deployments = clusterDeployments
+
+	k8sCluster.StatefulSets = []KubernetesStatefulSet{}
+	for _, statefulSet := range statefulSets {
+		if !scope.includes(statefulSet) {
+			continue
+		}
+		k8sCluster.StatefulSets = append(k8sCluster.StatefulSets, *newStatefulSet(statefulSet, graph))
+	}
+
+	k8sCluster.DaemonSets = []KubernetesDaemonSet{}
+	for _, daemonSet := range daemonSets {
+		if !scope.includes(daemonSet) {
+			continue
+		}
+		k8sCluster.DaemonSets = append(k8sCluster.DaemonSets, *newDaemonSet(daemonSet, graph))
+	}
+
+	k8sCluster.ReplicaSets = []KubernetesReplicaSet{}
+	for _, replicaSet := range replicaSets {
+		if !scope.includes(replicaSet) {
+			continue
+		}
+		k8sCluster.ReplicaSets = append(k8sCluster.ReplicaSets, *newReplicaSet(replicaSet, graph))
+	}
+
+	k8sCluster.Jobs = []KubernetesJob{}
+	for _, job := range jobs {
+		if !scope.includes(job) {
+			continue
+		}
+		k8sCluster.Jobs = append(k8sCluster.Jobs, *newJob(job, graph))
+	}
+
+	k8sCluster.CronJobs = []KubernetesCronJob{}
+	for _, cronJob := range cronJobs {
+		if !scope.includes(cronJob) {
+			continue
+		}
+		k8sCluster.CronJobs = append(k8sCluster.CronJobs, *newCronJob(cronJob, graph))
+	}
+
 	clusterNodes := []KubernetesNode{}
-	for _, node := range nodes.Items {
+	for _, node := range nodes {
 		clusterNode := &KubernetesNode{}
 		if node.Labels["kubeadm.alpha.kubernetes.io/role"] == "master" {
 			clusterNode.IsMaster = true
 		}
 		clusterNode.NodeName = node.Name
 		clusterNode.Conditions = node.Status.Conditions
+		clusterNode.Labels = node.Labels
+		clusterNode.Annotations = make(map[string]string)
+		for _, annotation := range capturer.NodeAnnotations {
+			if value, ok := node.Annotations[annotation]; ok {
+				clusterNode.Annotations[annotation] = value
+			}
+		}
+		clusterNode.Taints = node.Spec.Taints
+		clusterNode.Unschedulable = node.Spec.Unschedulable
+		clusterNode.Addresses = node.Status.Addresses
+		clusterNode.ProviderID = node.Spec.ProviderID
+		clusterNode.KubeletVersion = node.Status.NodeInfo.KubeletVersion
+		clusterNode.ContainerRuntimeVersion = node.Status.NodeInfo.ContainerRuntimeVersion
+		clusterNode.KernelVersion = node.Status.NodeInfo.KernelVersion
+		clusterNode.OSImage = node.Status.NodeInfo.OSImage
+		clusterNode.OperatingSystem = node.Status.NodeInfo.OperatingSystem
+		clusterNode.Architecture = node.Status.NodeInfo.Architecture
+		clusterNode.CreationTime = node.CreationTimestamp.Time
+		clusterNode.Capacity = newResources(node.Status.Capacity)
+		clusterNode.Allocatable = newResources(node.Status.Allocatable)
+		clusterNode.Requests = newResources(nil)
+		for _, requests := range nodeRequests[node.Name] {
+			clusterNode.Requests.add(requests)
+		}
+		clusterNode.Limits = newResources(nil)
+		for _, limits := range nodeLimits[node.Name] {
+			clusterNode.Limits.add(limits)
+		}
 
 		deploymentPods := []KubernetesPod{}
-		for _, pod := range pods.Items {
-			if pod.Spec.NodeName == node.Name {
-				deploymentPod := &KubernetesPod{}
-				deploymentPod.PodName = pod.Name
-				deploymentPod.NodeName = pod.Spec.NodeName
-				deploymentPod.ClusterName = pod.ClusterName
-				deploymentPod.Phase = string(pod.Status.Phase)
-
-				deploymentContainers := []KubernetesContainer{}
-				for _, container := range pod.Spec.Containers {
-					deploymentContainer := &KubernetesContainer{}
-					deploymentContainer.CantainerName = container.Name
-					deploymentContainer.ContainerImage = container.Image
-					deploymentContainer.Limit = make(map[string]string)
-					for k, v := range container.Resources.Limits {
-						limitJson, error := v.MarshalJSON()
-						if error == nil {
-							deploymentContainer.Limit[string(k)] = string(limitJson)
-						}
-					}
-					deploymentContainers = append(deploymentContainers, *deploymentContainer)
-				}
-				deploymentPod.Containers = deploymentContainers
-				deploymentPods = append(deploymentPods, *deploymentPod)
-				clusterNode.Pods = deploymentPods
-			}
+		for _, pod := range nodePods[node.Name] {
+			deploymentPods = append(deploymentPods, *newPod(pod, capturer.ClusterName, graph))
 		}
+		clusterNode.Pods = deploymentPods
 
 		clusterNodes = append(clusterNodes, *clusterNode)
 	}
 
 	k8sCluster.Nodes = clusterNodes
 
-	clusters := []KubernetesCluster{}
-	clusters = append(clusters, *k8sCluster)
-	k8sDeployments := &K8sDeployments{}
-	k8sDeployments.Clusters = clusters
+	// pending pods not yet bound to a node
+	unscheduledPods := []KubernetesPod{}
+	for _, pod := range nodePods[""] {
+		unscheduledPods = append(unscheduledPods, *newPod(pod, capturer.ClusterName, graph))
+	}
+	k8sCluster.UnscheduledPods = unscheduledPods
+
+	clusterServices := []KubernetesService{}
+	for _, service := range services {
+		if !scope.includes(service) {
+			continue
+		}
+		clusterService := &KubernetesService{}
+		clusterService.ServiceName = service.Name
+		clusterService.Namespace = service.Namespace
+		clusterService.Type = string(service.Spec.Type)
+		clusterService.ClusterIP = service.Spec.ClusterIP
+		clusterService.ExternalIPs = service.Spec.ExternalIPs
+		clusterService.Ports = service.Spec.Ports
+		clusterService.Selector = service.Spec.Selector
+		clusterService.SessionAffinity = string(service.Spec.SessionAffinity)
+		clusterService.LoadBalancerIngress = service.Status.LoadBalancer.Ingress
+		clusterServices = append(clusterServices, *clusterService)
+	}
 
-	// TODO: Write to database
+	k8sCluster.Services = clusterServices
+
+	var hash string
+	if capturer.WriteOnChange {
+		hash, err = snapshotHash(k8sCluster)
+		if err != nil {
+			return errors.New("Unable to hash cluster snapshot: " + err.Error())
+		}
+		if hash == capturer.lastHash {
+			glog.V(2).Infof("Cluster %s unchanged since last capture, skipping write", capturer.ClusterName)
+			return nil
+		}
+	}
+
+	selector := bson.M{"ClusterID": capturer.ClusterID}
+	if err := capturer.DB.Upsert(selector, *k8sCluster); err != nil {
+		return errors.New("Unable to write cluster " + capturer.ClusterName + ": " + err.Error())
+	}
+	capturer.lastHash = hash
 
 	return nil
 }
+
+func newPod(pod *v1.Pod, clusterName string, graph *ownerGraph) *KubernetesPod {
+	deploymentPod := &KubernetesPod{}
+	deploymentPod.PodName = pod.Name
+	deploymentPod.Namespace = pod.Namespace
+	deploymentPod.NodeName = pod.Spec.NodeName
+	deploymentPod.ClusterName = clusterName
+	deploymentPod.Labels = pod.Labels
+	deploymentPod.Phase = string(pod.Status.Phase)
+	deploymentPod.PodIP = pod.Status.PodIP
+	deploymentPod.HostIP = pod.Status.HostIP
+	deploymentPod.QOSClass = string(pod.Status.QOSClass)
+	deploymentPod.StartTime = timeValue(pod.Status.StartTime)
+	deploymentPod.Conditions = pod.Status.Conditions
+	deploymentPod.Tolerations = pod.Spec.Tolerations
+	deploymentPod.Affinity = pod.Spec.Affinity
+	deploymentPod.PriorityClassName = pod.Spec.PriorityClassName
+	deploymentPod.Priority = int32Value(pod.Spec.Priority)
+	if workload := graph.workload(pod); workload != nil {
+		deploymentPod.OwnerKind = workload.Kind
+		deploymentPod.OwnerName = workload.Name
+		deploymentPod.OwnerNamespace = workload.Namespace
+	}
+	deploymentPod.Requests, deploymentPod.Limits = podResources(pod)
+
+	deploymentContainers := []KubernetesContainer{}
+	for _, container := range pod.Spec.Containers {
+		deploymentContainers = append(deploymentContainers, *newContainer(container, pod.Status.ContainerStatuses))
+	}
+	deploymentPod.Containers = deploymentContainers
+
+	initContainers := []KubernetesContainer{}
+	for _, container := range pod.Spec.InitContainers {
+		initContainers = append(initContainers, *newContainer(container, pod.Status.InitContainerStatuses))
+	}
+	deploymentPod.InitContainers = initContainers
+
+	return deploymentPod
+}
+
+func newContainer(container v1.Container, statuses []v1.ContainerStatus) *KubernetesContainer {
+	deploymentContainer := &KubernetesContainer{}
+	deploymentContainer.CantainerName = container.Name
+	deploymentContainer.ContainerImage = container.Image
+	deploymentContainer.Requests = newResources(container.Resources.Requests)
+	deploymentContainer.Limits = newResources(container.Resources.Limits)
+
+	for _, status := range statuses {
+		if status.Name != container.Name {
+			continue
+		}
+		deploymentContainer.ImageID = status.ImageID
+		deploymentContainer.Ready = status.Ready
+		deploymentContainer.RestartCount = status.RestartCount
+		switch {
+		case status.State.Running != nil:
+			deploymentContainer.State = "Running"
+		case status.State.Waiting != nil:
+			deploymentContainer.State = "Waiting"
+			deploymentContainer.StateReason = status.State.Waiting.Reason
+		case status.State.Terminated != nil:
+			deploymentContainer.State = "Terminated"
+			deploymentContainer.StateReason = status.State.Terminated.Reason
+		}
+		if terminated := status.LastTerminationState.Terminated; terminated != nil {
+			deploymentContainer.LastTerminationReason = terminated.Reason
+			deploymentContainer.LastTerminationExitCode = terminated.ExitCode
+		}
+	}
+
+	return deploymentContainer
+}
+
+// Stop stops the informers, new ones are started by the next capture
+func (capturer *KubernetesCapturer) Stop() {
+	capturer.cache.stop()
+}
@@ -0,0 +1,84 @@
+package kubernetes
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxOwnerDepth bounds the walk up ownerReferences, Pod -> ReplicaSet -> Deployment
+// is the deepest chain created by the built-in controllers
+const maxOwnerDepth = 5
+
+type ownerKey struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// ownerGraph resolves pods to the workloads controlling them, the controllers
+// of replica sets and jobs are followed so pods are attributed to their
+// deployments and cron jobs as well
+type ownerGraph struct {
+	controllers map[ownerKey]*metav1.OwnerReference
+	pods        map[ownerKey][]string
+}
+
+func newOwnerGraph(replicaSets []*appsv1.ReplicaSet, jobs []*batchv1.Job) *ownerGraph {
+	graph := &ownerGraph{
+		controllers: make(map[ownerKey]*metav1.OwnerReference),
+		pods:        make(map[ownerKey][]string),
+	}
+
+	for _, replicaSet := range replicaSets {
+		if controller := metav1.GetControllerOf(replicaSet); controller != nil {
+			graph.controllers[ownerKey{"ReplicaSet", replicaSet.Namespace, replicaSet.Name}] = controller
+		}
+	}
+
+	for _, job := range jobs {
+		if controller := metav1.GetControllerOf(job); controller != nil {
+			graph.controllers[ownerKey{"Job", job.Namespace, job.Name}] = controller
+		}
+	}
+
+	return graph
+}
+
+// addPod lists a pod on every workload controlling it
+func (graph *ownerGraph) addPod(pod *v1.Pod) {
+	for _, owner := range graph.owners(pod) {
+		graph.pods[owner] = append(graph.pods[owner], pod.Name)
+	}
+}
+
+// owners returns the controllers of an object from its direct controller up to the top level workload
+func (graph *ownerGraph) owners(object metav1.Object) []ownerKey {
+	owners := []ownerKey{}
+	controller := metav1.GetControllerOf(object)
+	for controller != nil && len(owners) < maxOwnerDepth {
+		owner := ownerKey{controller.Kind, object.GetNamespace(), controller.Name}
+		owners = append(owners, owner)
+		controller = graph.controllers[owner]
+	}
+
+	return owners
+}
+
+// workload returns the top level workload of a pod, nil for bare pods
+func (graph *ownerGraph) workload(pod *v1.Pod) *ownerKey {
+	owners := graph.owners(pod)
+	if len(owners) == 0 {
+		return nil
+	}
+
+	return &owners[len(owners)-1]
+}
+
+// podNames returns the names of the pods controlled by a workload directly or through its replica sets or jobs
+func (graph *ownerGraph) podNames(kind string, namespace string, name string) []string {
+	podNames := []string{}
+	podNames = append(podNames, graph.pods[ownerKey{kind, namespace, name}]...)
+	return podNames
+}
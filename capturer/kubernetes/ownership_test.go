@@ -0,0 +1,160 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testNamespace = "default"
+
+func newTestObjectMeta(name string, controllerKind string, controllerName string) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{Namespace: testNamespace, Name: name}
+	if controllerKind != "" {
+		isController := true
+		meta.OwnerReferences = []metav1.OwnerReference{
+			{Kind: controllerKind, Name: controllerName, Controller: &isController},
+		}
+	}
+
+	return meta
+}
+
+func newTestPod(name string, controllerKind string, controllerName string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: newTestObjectMeta(name, controllerKind, controllerName)}
+}
+
+func newTestReplicaSet(name string, controllerKind string, controllerName string) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{ObjectMeta: newTestObjectMeta(name, controllerKind, controllerName)}
+}
+
+func newTestJob(name string, controllerKind string, controllerName string) *batchv1.Job {
+	return &batchv1.Job{ObjectMeta: newTestObjectMeta(name, controllerKind, controllerName)}
+}
+
+func TestOwners(t *testing.T) {
+	replicaSets := []*appsv1.ReplicaSet{
+		newTestReplicaSet("web-5d8f", "Deployment", "web"),
+		newTestReplicaSet("standalone", "", ""),
+		newTestReplicaSet("loop", "ReplicaSet", "loop"),
+	}
+	jobs := []*batchv1.Job{
+		newTestJob("backup-1583488800", "CronJob", "backup"),
+		newTestJob("migrate", "", ""),
+	}
+	graph := newOwnerGraph(replicaSets, jobs)
+
+	notController := false
+	adopted := newTestPod("adopted", "", "")
+	adopted.OwnerReferences = []metav1.OwnerReference{
+		{Kind: "ReplicaSet", Name: "web-5d8f", Controller: &notController},
+	}
+
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		owners   []ownerKey
+		workload *ownerKey
+	}{
+		{
+			name: "deployment pod",
+			pod:  newTestPod("web-5d8f-x7k2p", "ReplicaSet", "web-5d8f"),
+			owners: []ownerKey{
+				{"ReplicaSet", testNamespace, "web-5d8f"},
+				{"Deployment", testNamespace, "web"},
+			},
+			workload: &ownerKey{"Deployment", testNamespace, "web"},
+		},
+		{
+			name:     "replica set pod",
+			pod:      newTestPod("standalone-q9z4m", "ReplicaSet", "standalone"),
+			owners:   []ownerKey{{"ReplicaSet", testNamespace, "standalone"}},
+			workload: &ownerKey{"ReplicaSet", testNamespace, "standalone"},
+		},
+		{
+			name: "cron job pod",
+			pod:  newTestPod("backup-1583488800-8vx2c", "Job", "backup-1583488800"),
+			owners: []ownerKey{
+				{"Job", testNamespace, "backup-1583488800"},
+				{"CronJob", testNamespace, "backup"},
+			},
+			workload: &ownerKey{"CronJob", testNamespace, "backup"},
+		},
+		{
+			name:     "job pod",
+			pod:      newTestPod("migrate-t2m6d", "Job", "migrate"),
+			owners:   []ownerKey{{"Job", testNamespace, "migrate"}},
+			workload: &ownerKey{"Job", testNamespace, "migrate"},
+		},
+		{
+			name:     "stateful set pod",
+			pod:      newTestPod("db-0", "StatefulSet", "db"),
+			owners:   []ownerKey{{"StatefulSet", testNamespace, "db"}},
+			workload: &ownerKey{"StatefulSet", testNamespace, "db"},
+		},
+		{
+			name:   "bare pod",
+			pod:    newTestPod("debug", "", ""),
+			owners: []ownerKey{},
+		},
+		{
+			name:   "owner that is not the controller",
+			pod:    adopted,
+			owners: []ownerKey{},
+		},
+		{
+			name: "controller cycle stops at the maximum depth",
+			pod:  newTestPod("loop-b7n3s", "ReplicaSet", "loop"),
+			owners: []ownerKey{
+				{"ReplicaSet", testNamespace, "loop"},
+				{"ReplicaSet", testNamespace, "loop"},
+				{"ReplicaSet", testNamespace, "loop"},
+				{"ReplicaSet", testNamespace, "loop"},
+				{"ReplicaSet", testNamespace, "loop"},
+			},
+			workload: &ownerKey{"ReplicaSet", testNamespace, "loop"},
+		},
+	}
+
+	for _, test := range tests {
+		if owners := graph.owners(test.pod); !reflect.DeepEqual(owners, test.owners) {
+			t.Errorf("%s: expected owners %v, got %v", test.name, test.owners, owners)
+		}
+		if workload := graph.workload(test.pod); !reflect.DeepEqual(workload, test.workload) {
+			t.Errorf("%s: expected workload %v, got %v", test.name, test.workload, workload)
+		}
+	}
+}
+
+func TestPodNames(t *testing.T) {
+	graph := newOwnerGraph(
+		[]*appsv1.ReplicaSet{newTestReplicaSet("web-5d8f", "Deployment", "web")},
+		[]*batchv1.Job{newTestJob("backup-1583488800", "CronJob", "backup")},
+	)
+	graph.addPod(newTestPod("web-5d8f-x7k2p", "ReplicaSet", "web-5d8f"))
+	graph.addPod(newTestPod("web-5d8f-m4c8w", "ReplicaSet", "web-5d8f"))
+	graph.addPod(newTestPod("backup-1583488800-8vx2c", "Job", "backup-1583488800"))
+	graph.addPod(newTestPod("debug", "", ""))
+
+	tests := []struct {
+		kind string
+		name string
+		pods []string
+	}{
+		{"Deployment", "web", []string{"web-5d8f-x7k2p", "web-5d8f-m4c8w"}},
+		{"ReplicaSet", "web-5d8f", []string{"web-5d8f-x7k2p", "web-5d8f-m4c8w"}},
+		{"CronJob", "backup", []string{"backup-1583488800-8vx2c"}},
+		{"Job", "backup-1583488800", []string{"backup-1583488800-8vx2c"}},
+		{"Deployment", "api", []string{}},
+	}
+
+	for _, test := range tests {
+		if pods := graph.podNames(test.kind, testNamespace, test.name); !reflect.DeepEqual(pods, test.pods) {
+			t.Errorf("Expected pods %v of %s %s, got %v", test.pods, test.kind, test.name, pods)
+		}
+	}
+}
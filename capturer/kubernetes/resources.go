@@ -0,0 +1,117 @@
+package kubernetes
+
+import (
+	"sort"
+
+	"k8s.io/api/core/v1"
+)
+
+type KubernetesResource struct {
+	Name  string `json:"Name" bson:"Name"`
+	Value int64  `json:"Value" bson:"Value"`
+}
+
+// KubernetesResources holds quantities as numbers, CPU in millicores and
+// Memory in bytes. Other resources, e.g. ephemeral-storage or nvidia.com/gpu,
+// are kept in Extended by name since their names aren't valid field names.
+type KubernetesResources struct {
+	CPU      int64                `json:"CPU" bson:"CPU"`
+	Memory   int64                `json:"Memory" bson:"Memory"`
+	Extended []KubernetesResource `json:"Extended" bson:"Extended"`
+}
+
+func newResources(resourceList v1.ResourceList) KubernetesResources {
+	resources := KubernetesResources{
+		Extended: []KubernetesResource{},
+	}
+	for name, quantity := range resourceList {
+		switch name {
+		case v1.ResourceCPU:
+			resources.CPU = quantity.MilliValue()
+		case v1.ResourceMemory:
+			resources.Memory = quantity.Value()
+		default:
+			resources.Extended = append(resources.Extended, KubernetesResource{
+				Name:  string(name),
+				Value: quantity.Value(),
+			})
+		}
+	}
+	sortResources(resources.Extended)
+
+	return resources
+}
+
+// podResources returns the effective requests and limits of a pod, the sum of
+// its containers' or the largest of its init containers' when that is higher,
+// as init containers run one at a time before the others start
+func podResources(pod *v1.Pod) (KubernetesResources, KubernetesResources) {
+	requests := newResources(nil)
+	limits := newResources(nil)
+	for _, container := range pod.Spec.Containers {
+		requests.add(newResources(container.Resources.Requests))
+		limits.add(newResources(container.Resources.Limits))
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		requests.max(newResources(container.Resources.Requests))
+		limits.max(newResources(container.Resources.Limits))
+	}
+
+	return requests, limits
+}
+
+// add sums other into resources
+func (resources *KubernetesResources) add(other KubernetesResources) {
+	resources.CPU += other.CPU
+	resources.Memory += other.Memory
+
+	for _, otherResource := range other.Extended {
+		found := false
+		for i := range resources.Extended {
+			if resources.Extended[i].Name == otherResource.Name {
+				resources.Extended[i].Value += otherResource.Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			resources.Extended = append(resources.Extended, otherResource)
+		}
+	}
+	sortResources(resources.Extended)
+}
+
+// max raises each resource to other's quantity where that is higher
+func (resources *KubernetesResources) max(other KubernetesResources) {
+	if other.CPU > resources.CPU {
+		resources.CPU = other.CPU
+	}
+	if other.Memory > resources.Memory {
+		resources.Memory = other.Memory
+	}
+
+	for _, otherResource := range other.Extended {
+		found := false
+		for i := range resources.Extended {
+			if resources.Extended[i].Name == otherResource.Name {
+				if otherResource.Value > resources.Extended[i].Value {
+					resources.Extended[i].Value = otherResource.Value
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			resources.Extended = append(resources.Extended, otherResource)
+		}
+	}
+	sortResources(resources.Extended)
+}
+
+// sortResources keeps extended resources in a stable order across snapshots
+func sortResources(resources []KubernetesResource) {
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Name < resources[j].Name
+	})
+}
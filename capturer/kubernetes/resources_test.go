@@ -0,0 +1,98 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func newTestContainer(cpu string, memory string, gpus string) v1.Container {
+	resourceList := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse(cpu),
+		v1.ResourceMemory: resource.MustParse(memory),
+	}
+	if gpus != "" {
+		resourceList["nvidia.com/gpu"] = resource.MustParse(gpus)
+	}
+
+	return v1.Container{
+		Resources: v1.ResourceRequirements{
+			Requests: resourceList,
+			Limits:   resourceList,
+		},
+	}
+}
+
+func TestPodResources(t *testing.T) {
+	tests := []struct {
+		name           string
+		containers     []v1.Container
+		initContainers []v1.Container
+		cpu            int64
+		memory         int64
+		gpus           int64
+	}{
+		{
+			name:       "containers are summed",
+			containers: []v1.Container{newTestContainer("100m", "64Mi", ""), newTestContainer("200m", "128Mi", "")},
+			cpu:        300,
+			memory:     192 << 20,
+		},
+		{
+			name:           "smaller init containers are ignored",
+			containers:     []v1.Container{newTestContainer("100m", "64Mi", ""), newTestContainer("200m", "128Mi", "")},
+			initContainers: []v1.Container{newTestContainer("250m", "128Mi", "")},
+			cpu:            300,
+			memory:         192 << 20,
+		},
+		{
+			name:           "larger init container raises each resource on its own",
+			containers:     []v1.Container{newTestContainer("100m", "64Mi", "")},
+			initContainers: []v1.Container{newTestContainer("500m", "32Mi", "")},
+			cpu:            500,
+			memory:         64 << 20,
+		},
+		{
+			name:           "init containers are not summed",
+			containers:     []v1.Container{newTestContainer("100m", "64Mi", "")},
+			initContainers: []v1.Container{newTestContainer("400m", "256Mi", ""), newTestContainer("300m", "512Mi", "")},
+			cpu:            400,
+			memory:         512 << 20,
+		},
+		{
+			name:           "extended resources follow the same rule",
+			containers:     []v1.Container{newTestContainer("100m", "64Mi", "1"), newTestContainer("100m", "64Mi", "1")},
+			initContainers: []v1.Container{newTestContainer("100m", "64Mi", "3")},
+			cpu:            200,
+			memory:         128 << 20,
+			gpus:           3,
+		},
+	}
+
+	for _, test := range tests {
+		pod := &v1.Pod{
+			Spec: v1.PodSpec{
+				Containers:     test.containers,
+				InitContainers: test.initContainers,
+			},
+		}
+		requests, limits := podResources(pod)
+		for kind, resources := range map[string]KubernetesResources{"requests": requests, "limits": limits} {
+			if resources.CPU != test.cpu || resources.Memory != test.memory {
+				t.Errorf("%s: expected %s of %dm CPU and %d bytes, got %dm CPU and %d bytes",
+					test.name, kind, test.cpu, test.memory, resources.CPU, resources.Memory)
+			}
+
+			gpus := int64(0)
+			for _, extended := range resources.Extended {
+				if extended.Name == "nvidia.com/gpu" {
+					gpus = extended.Value
+				}
+			}
+			if gpus != test.gpus {
+				t.Errorf("%s: expected %s of %d GPUs, got %d", test.name, kind, test.gpus, gpus)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package kubernetes
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OptOutAnnotation set to "true" on a namespace or a workload excludes it from
+// capture, together with the pods and replica sets or jobs it controls
+const OptOutAnnotation = "hyperpilot.io/ingestor-exclude"
+
+// captureScope decides which namespaced objects of a snapshot are recorded,
+// label and field selectors and excluded namespaces are already applied by
+// the API server when the informers list and watch, or by the cache for pods.
+type captureScope struct {
+	namespaces map[string]bool
+	optedOut   map[ownerKey]bool
+	graph      *ownerGraph
+}
+
+func newCaptureScope(namespaces []string, graph *ownerGraph) *captureScope {
+	scope := &captureScope{
+		namespaces: make(map[string]bool),
+		optedOut:   make(map[ownerKey]bool),
+		graph:      graph,
+	}
+	for _, namespace := range namespaces {
+		scope.namespaces[namespace] = true
+	}
+
+	return scope
+}
+
+// optOut records an object carrying the opt-out annotation, it must be called
+// for every namespace and workload before includes
+func (scope *captureScope) optOut(kind string, object metav1.Object) {
+	if optedOut(object) {
+		scope.optedOut[ownerKey{kind, object.GetNamespace(), object.GetName()}] = true
+	}
+}
+
+// includes returns whether a namespaced object is captured, objects of opted
+// out namespaces or controlled by opted out workloads are left out
+func (scope *captureScope) includes(object metav1.Object) bool {
+	namespace := object.GetNamespace()
+	if len(scope.namespaces) > 0 && !scope.namespaces[namespace] {
+		return false
+	}
+
+	if scope.optedOut[ownerKey{"Namespace", "", namespace}] || optedOut(object) {
+		return false
+	}
+
+	for _, owner := range scope.graph.owners(object) {
+		if scope.optedOut[owner] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func optedOut(object metav1.Object) bool {
+	return object.GetAnnotations()[OptOutAnnotation] == "true"
+}
@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func annotateOptOut(meta *metav1.ObjectMeta) {
+	meta.Annotations = map[string]string{OptOutAnnotation: "true"}
+}
+
+func TestCaptureScope(t *testing.T) {
+	optedOutDeployment := &appsv1.Deployment{ObjectMeta: newTestObjectMeta("web", "", "")}
+	annotateOptOut(&optedOutDeployment.ObjectMeta)
+	deployment := &appsv1.Deployment{ObjectMeta: newTestObjectMeta("api", "", "")}
+	optedOutJob := newTestJob("migrate", "", "")
+	annotateOptOut(&optedOutJob.ObjectMeta)
+	optedOutPod := newTestPod("debug", "", "")
+	annotateOptOut(&optedOutPod.ObjectMeta)
+
+	optedOutNamespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sandbox"}}
+	annotateOptOut(&optedOutNamespace.ObjectMeta)
+	sandboxPod := newTestPod("sandbox-pod", "", "")
+	sandboxPod.Namespace = "sandbox"
+	otherPod := newTestPod("other-pod", "", "")
+	otherPod.Namespace = "other"
+
+	replicaSets := []*appsv1.ReplicaSet{
+		newTestReplicaSet("web-5d8f", "Deployment", "web"),
+		newTestReplicaSet("api-7c9b", "Deployment", "api"),
+	}
+	jobs := []*batchv1.Job{optedOutJob}
+	graph := newOwnerGraph(replicaSets, jobs)
+
+	scope := newCaptureScope([]string{testNamespace, "sandbox"}, graph)
+	scope.optOut("Namespace", optedOutNamespace)
+	scope.optOut("Deployment", optedOutDeployment)
+	scope.optOut("Deployment", deployment)
+	for _, replicaSet := range replicaSets {
+		scope.optOut("ReplicaSet", replicaSet)
+	}
+	scope.optOut("Job", optedOutJob)
+
+	tests := []struct {
+		name     string
+		object   metav1.Object
+		included bool
+	}{
+		{"opted out deployment", optedOutDeployment, false},
+		{"replica set of opted out deployment", replicaSets[0], false},
+		{"pod of opted out deployment", newTestPod("web-5d8f-x7k2p", "ReplicaSet", "web-5d8f"), false},
+		{"deployment", deployment, true},
+		{"replica set of deployment", replicaSets[1], true},
+		{"pod of deployment", newTestPod("api-7c9b-r2d4f", "ReplicaSet", "api-7c9b"), true},
+		{"pod of opted out job", newTestPod("migrate-t2m6d", "Job", "migrate"), false},
+		{"opted out pod", optedOutPod, false},
+		{"bare pod", newTestPod("shell", "", ""), true},
+		{"pod of opted out namespace", sandboxPod, false},
+		{"pod outside the namespaces", otherPod, false},
+	}
+
+	for _, test := range tests {
+		if included := scope.includes(test.object); included != test.included {
+			t.Errorf("%s: expected included %t, got %t", test.name, test.included, included)
+		}
+	}
+}
+
+func TestMatchesPod(t *testing.T) {
+	clusterCache, err := newClusterCache(nil, ClusterConfig{
+		Name:              "shared",
+		ExcludeNamespaces: []string{"kube-system"},
+		LabelSelector:     "team!=sandbox",
+		FieldSelector:     "status.phase!=Failed",
+	})
+	if err != nil {
+		t.Fatalf("Unable to create cache: %s", err.Error())
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		team      string
+		phase     v1.PodPhase
+		matches   bool
+	}{
+		{"matching pod", testNamespace, "web", v1.PodRunning, true},
+		{"pod without labels", testNamespace, "", v1.PodRunning, true},
+		{"pod of excluded namespace", "kube-system", "web", v1.PodRunning, false},
+		{"pod excluded by label", testNamespace, "sandbox", v1.PodRunning, false},
+		{"pod excluded by field", testNamespace, "web", v1.PodFailed, false},
+	}
+
+	for _, test := range tests {
+		pod := newTestPod("pod", "", "")
+		pod.Namespace = test.namespace
+		pod.Status.Phase = test.phase
+		if test.team != "" {
+			pod.Labels = map[string]string{"team": test.team}
+		}
+		if matches := clusterCache.matchesPod(pod); matches != test.matches {
+			t.Errorf("%s: expected matches %t, got %t", test.name, test.matches, matches)
+		}
+	}
+}
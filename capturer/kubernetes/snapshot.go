@@ -0,0 +1,60 @@
+package kubernetes
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// lessObject orders objects by namespace then name, listers return them in
+// map order which would make every snapshot differ
+func lessObject(a metav1.Object, b metav1.Object) bool {
+	if a.GetNamespace() != b.GetNamespace() {
+		return a.GetNamespace() < b.GetNamespace()
+	}
+	return a.GetName() < b.GetName()
+}
+
+// snapshotHash hashes the snapshot for writeOnChange, leaving out the node
+// heartbeat and pod probe times as they change without the cluster changing
+func snapshotHash(k8sCluster *KubernetesCluster) (string, error) {
+	snapshot := *k8sCluster
+	snapshot.Nodes = []KubernetesNode{}
+	for _, node := range k8sCluster.Nodes {
+		conditions := []v1.NodeCondition{}
+		for _, condition := range node.Conditions {
+			condition.LastHeartbeatTime = metav1.Time{}
+			conditions = append(conditions, condition)
+		}
+		node.Conditions = conditions
+		node.Pods = withoutProbeTimes(node.Pods)
+		snapshot.Nodes = append(snapshot.Nodes, node)
+	}
+	snapshot.UnscheduledPods = withoutProbeTimes(k8sCluster.UnscheduledPods)
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", err
+	}
+	hash := sha1.Sum(data)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func withoutProbeTimes(pods []KubernetesPod) []KubernetesPod {
+	snapshotPods := []KubernetesPod{}
+	for _, pod := range pods {
+		conditions := []v1.PodCondition{}
+		for _, condition := range pod.Conditions {
+			condition.LastProbeTime = metav1.Time{}
+			conditions = append(conditions, condition)
+		}
+		pod.Conditions = conditions
+		snapshotPods = append(snapshotPods, pod)
+	}
+
+	return snapshotPods
+}
@@ -0,0 +1,231 @@
+package kubernetes
+
+import (
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type KubernetesStatefulSet struct {
+	Name                string                `json:"Name" bson:"Name"`
+	Namespace           string                `json:"Namespace" bson:"Namespace"`
+	SelfLink            string                `json:"SelfLink" bson:"SelfLink"`
+	Labels              map[string]string     `json:"Labels" bson:"Labels"`
+	Selector            map[string]string     `json:"Selector" bson:"Selector"`
+	NodeSelector        map[string]string     `json:"NodeSelector" bson:"NodeSelector"`
+	Containers          []KubernetesContainer `json:"Containers" bson:"Containers"`
+	ServiceName         string                `json:"ServiceName" bson:"ServiceName"`
+	PodManagementPolicy string                `json:"PodManagementPolicy" bson:"PodManagementPolicy"`
+	UpdateStrategy      string                `json:"UpdateStrategy" bson:"UpdateStrategy"`
+	Replicas            int32                 `json:"Replicas" bson:"Replicas"`
+	ReadyReplicas       int32                 `json:"ReadyReplicas" bson:"ReadyReplicas"`
+	CurrentReplicas     int32                 `json:"CurrentReplicas" bson:"CurrentReplicas"`
+	UpdatedReplicas     int32                 `json:"UpdatedReplicas" bson:"UpdatedReplicas"`
+	Pods                []string              `json:"Pods" bson:"Pods"`
+}
+
+type KubernetesDaemonSet struct {
+	Name                   string                `json:"Name" bson:"Name"`
+	Namespace              string                `json:"Namespace" bson:"Namespace"`
+	SelfLink               string                `json:"SelfLink" bson:"SelfLink"`
+	Labels                 map[string]string     `json:"Labels" bson:"Labels"`
+	Selector               map[string]string     `json:"Selector" bson:"Selector"`
+	NodeSelector           map[string]string     `json:"NodeSelector" bson:"NodeSelector"`
+	Containers             []KubernetesContainer `json:"Containers" bson:"Containers"`
+	UpdateStrategy         string                `json:"UpdateStrategy" bson:"UpdateStrategy"`
+	DesiredNumberScheduled int32                 `json:"DesiredNumberScheduled" bson:"DesiredNumberScheduled"`
+	CurrentNumberScheduled int32                 `json:"CurrentNumberScheduled" bson:"CurrentNumberScheduled"`
+	UpdatedNumberScheduled int32                 `json:"UpdatedNumberScheduled" bson:"UpdatedNumberScheduled"`
+	NumberReady            int32                 `json:"NumberReady" bson:"NumberReady"`
+	NumberAvailable        int32                 `json:"NumberAvailable" bson:"NumberAvailable"`
+	Pods                   []string              `json:"Pods" bson:"Pods"`
+}
+
+type KubernetesReplicaSet struct {
+	Name              string                `json:"Name" bson:"Name"`
+	Namespace         string                `json:"Namespace" bson:"Namespace"`
+	SelfLink          string                `json:"SelfLink" bson:"SelfLink"`
+	Labels            map[string]string     `json:"Labels" bson:"Labels"`
+	Selector          map[string]string     `json:"Selector" bson:"Selector"`
+	NodeSelector      map[string]string     `json:"NodeSelector" bson:"NodeSelector"`
+	Containers        []KubernetesContainer `json:"Containers" bson:"Containers"`
+	Replicas          int32                 `json:"Replicas" bson:"Replicas"`
+	ReadyReplicas     int32                 `json:"ReadyReplicas" bson:"ReadyReplicas"`
+	AvailableReplicas int32                 `json:"AvailableReplicas" bson:"AvailableReplicas"`
+	Pods              []string              `json:"Pods" bson:"Pods"`
+}
+
+type KubernetesJob struct {
+	Name           string                `json:"Name" bson:"Name"`
+	Namespace      string                `json:"Namespace" bson:"Namespace"`
+	SelfLink       string                `json:"SelfLink" bson:"SelfLink"`
+	Labels         map[string]string     `json:"Labels" bson:"Labels"`
+	Selector       map[string]string     `json:"Selector" bson:"Selector"`
+	NodeSelector   map[string]string     `json:"NodeSelector" bson:"NodeSelector"`
+	Containers     []KubernetesContainer `json:"Containers" bson:"Containers"`
+	Parallelism    int32                 `json:"Parallelism" bson:"Parallelism"`
+	Completions    int32                 `json:"Completions" bson:"Completions"`
+	Active         int32                 `json:"Active" bson:"Active"`
+	Succeeded      int32                 `json:"Succeeded" bson:"Succeeded"`
+	Failed         int32                 `json:"Failed" bson:"Failed"`
+	StartTime      time.Time             `json:"StartTime" bson:"StartTime"`
+	CompletionTime time.Time             `json:"CompletionTime" bson:"CompletionTime"`
+	Pods           []string              `json:"Pods" bson:"Pods"`
+}
+
+type KubernetesCronJob struct {
+	Name              string                `json:"Name" bson:"Name"`
+	Namespace         string                `json:"Namespace" bson:"Namespace"`
+	SelfLink          string                `json:"SelfLink" bson:"SelfLink"`
+	Labels            map[string]string     `json:"Labels" bson:"Labels"`
+	NodeSelector      map[string]string     `json:"NodeSelector" bson:"NodeSelector"`
+	Containers        []KubernetesContainer `json:"Containers" bson:"Containers"`
+	Schedule          string                `json:"Schedule" bson:"Schedule"`
+	Suspend           bool                  `json:"Suspend" bson:"Suspend"`
+	ConcurrencyPolicy string                `json:"ConcurrencyPolicy" bson:"ConcurrencyPolicy"`
+	ActiveJobs        []string              `json:"ActiveJobs" bson:"ActiveJobs"`
+	LastScheduleTime  time.Time             `json:"LastScheduleTime" bson:"LastScheduleTime"`
+	Pods              []string              `json:"Pods" bson:"Pods"`
+}
+
+func newStatefulSet(statefulSet *appsv1.StatefulSet, graph *ownerGraph) *KubernetesStatefulSet {
+	clusterStatefulSet := &KubernetesStatefulSet{}
+	clusterStatefulSet.Name = statefulSet.Name
+	clusterStatefulSet.Namespace = statefulSet.Namespace
+	clusterStatefulSet.SelfLink = statefulSet.SelfLink
+	clusterStatefulSet.Labels = statefulSet.Labels
+	clusterStatefulSet.Selector = selectorLabels(statefulSet.Spec.Selector)
+	clusterStatefulSet.NodeSelector = statefulSet.Spec.Template.Spec.NodeSelector
+	clusterStatefulSet.Containers = templateContainers(statefulSet.Spec.Template.Spec)
+	clusterStatefulSet.ServiceName = statefulSet.Spec.ServiceName
+	clusterStatefulSet.PodManagementPolicy = string(statefulSet.Spec.PodManagementPolicy)
+	clusterStatefulSet.UpdateStrategy = string(statefulSet.Spec.UpdateStrategy.Type)
+	clusterStatefulSet.Replicas = int32Value(statefulSet.Spec.Replicas)
+	clusterStatefulSet.ReadyReplicas = statefulSet.Status.ReadyReplicas
+	clusterStatefulSet.CurrentReplicas = statefulSet.Status.CurrentReplicas
+	clusterStatefulSet.UpdatedReplicas = statefulSet.Status.UpdatedReplicas
+	clusterStatefulSet.Pods = graph.podNames("StatefulSet", statefulSet.Namespace, statefulSet.Name)
+
+	return clusterStatefulSet
+}
+
+func newDaemonSet(daemonSet *appsv1.DaemonSet, graph *ownerGraph) *KubernetesDaemonSet {
+	clusterDaemonSet := &KubernetesDaemonSet{}
+	clusterDaemonSet.Name = daemonSet.Name
+	clusterDaemonSet.Namespace = daemonSet.Namespace
+	clusterDaemonSet.SelfLink = daemonSet.SelfLink
+	clusterDaemonSet.Labels = daemonSet.Labels
+	clusterDaemonSet.Selector = selectorLabels(daemonSet.Spec.Selector)
+	clusterDaemonSet.NodeSelector = daemonSet.Spec.Template.Spec.NodeSelector
+	clusterDaemonSet.Containers = templateContainers(daemonSet.Spec.Template.Spec)
+	clusterDaemonSet.UpdateStrategy = string(daemonSet.Spec.UpdateStrategy.Type)
+	clusterDaemonSet.DesiredNumberScheduled = daemonSet.Status.DesiredNumberScheduled
+	clusterDaemonSet.CurrentNumberScheduled = daemonSet.Status.CurrentNumberScheduled
+	clusterDaemonSet.UpdatedNumberScheduled = daemonSet.Status.UpdatedNumberScheduled
+	clusterDaemonSet.NumberReady = daemonSet.Status.NumberReady
+	clusterDaemonSet.NumberAvailable = daemonSet.Status.NumberAvailable
+	clusterDaemonSet.Pods = graph.podNames("DaemonSet", daemonSet.Namespace, daemonSet.Name)
+
+	return clusterDaemonSet
+}
+
+func newReplicaSet(replicaSet *appsv1.ReplicaSet, graph *ownerGraph) *KubernetesReplicaSet {
+	clusterReplicaSet := &KubernetesReplicaSet{}
+	clusterReplicaSet.Name = replicaSet.Name
+	clusterReplicaSet.Namespace = replicaSet.Namespace
+	clusterReplicaSet.SelfLink = replicaSet.SelfLink
+	clusterReplicaSet.Labels = replicaSet.Labels
+	clusterReplicaSet.Selector = selectorLabels(replicaSet.Spec.Selector)
+	clusterReplicaSet.NodeSelector = replicaSet.Spec.Template.Spec.NodeSelector
+	clusterReplicaSet.Containers = templateContainers(replicaSet.Spec.Template.Spec)
+	clusterReplicaSet.Replicas = int32Value(replicaSet.Spec.Replicas)
+	clusterReplicaSet.ReadyReplicas = replicaSet.Status.ReadyReplicas
+	clusterReplicaSet.AvailableReplicas = replicaSet.Status.AvailableReplicas
+	clusterReplicaSet.Pods = graph.podNames("ReplicaSet", replicaSet.Namespace, replicaSet.Name)
+
+	return clusterReplicaSet
+}
+
+func newJob(job *batchv1.Job, graph *ownerGraph) *KubernetesJob {
+	clusterJob := &KubernetesJob{}
+	clusterJob.Name = job.Name
+	clusterJob.Namespace = job.Namespace
+	clusterJob.SelfLink = job.SelfLink
+	clusterJob.Labels = job.Labels
+	clusterJob.Selector = selectorLabels(job.Spec.Selector)
+	clusterJob.NodeSelector = job.Spec.Template.Spec.NodeSelector
+	clusterJob.Containers = templateContainers(job.Spec.Template.Spec)
+	clusterJob.Parallelism = int32Value(job.Spec.Parallelism)
+	clusterJob.Completions = int32Value(job.Spec.Completions)
+	clusterJob.Active = job.Status.Active
+	clusterJob.Succeeded = job.Status.Succeeded
+	clusterJob.Failed = job.Status.Failed
+	clusterJob.StartTime = timeValue(job.Status.StartTime)
+	clusterJob.CompletionTime = timeValue(job.Status.CompletionTime)
+	clusterJob.Pods = graph.podNames("Job", job.Namespace, job.Name)
+
+	return clusterJob
+}
+
+func newCronJob(cronJob *batchv1beta1.CronJob, graph *ownerGraph) *KubernetesCronJob {
+	jobSpec := cronJob.Spec.JobTemplate.Spec
+
+	clusterCronJob := &KubernetesCronJob{}
+	clusterCronJob.Name = cronJob.Name
+	clusterCronJob.Namespace = cronJob.Namespace
+	clusterCronJob.SelfLink = cronJob.SelfLink
+	clusterCronJob.Labels = cronJob.Labels
+	clusterCronJob.NodeSelector = jobSpec.Template.Spec.NodeSelector
+	clusterCronJob.Containers = templateContainers(jobSpec.Template.Spec)
+	clusterCronJob.Schedule = cronJob.Spec.Schedule
+	clusterCronJob.Suspend = cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+	clusterCronJob.ConcurrencyPolicy = string(cronJob.Spec.ConcurrencyPolicy)
+	clusterCronJob.LastScheduleTime = timeValue(cronJob.Status.LastScheduleTime)
+
+	activeJobs := []string{}
+	for _, job := range cronJob.Status.Active {
+		activeJobs = append(activeJobs, job.Name)
+	}
+	clusterCronJob.ActiveJobs = activeJobs
+	clusterCronJob.Pods = graph.podNames("CronJob", cronJob.Namespace, cronJob.Name)
+
+	return clusterCronJob
+}
+
+// templateContainers returns the containers a pod template creates with their resources
+func templateContainers(spec v1.PodSpec) []KubernetesContainer {
+	containers := []KubernetesContainer{}
+	for _, container := range spec.Containers {
+		containers = append(containers, *newContainer(container, nil))
+	}
+
+	return containers
+}
+
+func selectorLabels(selector *metav1.LabelSelector) map[string]string {
+	if selector == nil {
+		return nil
+	}
+
+	return selector.MatchLabels
+}
+
+func int32Value(value *int32) int32 {
+	if value == nil {
+		return 0
+	}
+
+	return *value
+}
+
+func timeValue(value *metav1.Time) time.Time {
+	if value == nil {
+		return time.Time{}
+	}
+
+	return value.Time
+}
@@ -45,7 +45,7 @@ func (db MongoDB) connect() (*mgo.Session, error) {
 	return sess, nil
 }
 
-func (db MongoDB) Insert(data interface{}) error {
+func (db MongoDB) Insert(data ...interface{}) error {
 	session, sessionErr := db.connect()
 	if sessionErr != nil {
 		return errors.New("Unable to connect mongo: " + sessionErr.Error())
@@ -55,7 +55,7 @@ func (db MongoDB) Insert(data interface{}) error {
 
 	c := session.DB(db.DatabaseName).C(db.TableName)
 
-	err := c.Insert(data)
+	err := c.Insert(data...)
 	if err != nil {
 		return errors.New("Unable to insert data: " + err.Error())
 	}